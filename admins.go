@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+// configuredAdmins are set with TWIN_LUNCH_ADMINS, they can't be removed with commands so that there's always an admin
+var configuredAdmins = make(map[string]struct{})
+
+// TwinLunchAdmin is an admin added with /twinlunch-admin-add, keyed by user ID.
+type TwinLunchAdmin struct {
+	AddedBy string
+	AddedAt time.Time
+}
+
+func adminKey(user string) *datastore.Key {
+	return datastore.NameKey("TwinLunchAdmin", user, nil)
+}
+
+// loadTwinLunchAdmins merges the configured admins with the ones from datastore.
+func loadTwinLunchAdmins(ctx context.Context) {
+	var keys []*datastore.Key
+
+	if err := withDatastore(ctx, func(ctx context.Context) error {
+		var err error
+		keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchAdmin").KeysOnly(), nil)
+		return err
+	}); err != nil {
+		logger.Fatalf("error reading twin lunch admins from datastore %s", err)
+	}
+
+	for admin := range configuredAdmins {
+		twinLunchAdmins[admin] = struct{}{}
+	}
+	for _, key := range keys {
+		twinLunchAdmins[key.Name] = struct{}{}
+	}
+
+	logger.Printf("loaded %d twin lunch admins", len(twinLunchAdmins))
+}
+
+func handleAdminAddCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "adminAddUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+
+	if _, ok := twinLunchAdmins[user]; ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "alreadyAdmin", messageData{"User": user}))
+		return
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, adminKey(user), &TwinLunchAdmin{command.UserID, time.Now()})
+		return err
+	}); err != nil {
+		commandLogger(command).Printf("error writing admin in datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	twinLunchAdmins[user] = struct{}{}
+	recordAudit(auditActionAdminAdded, command.UserID, user)
+
+	updateHome(user)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "adminAdded", messageData{"User": user}))
+}
+
+func handleAdminRemoveCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "adminRemoveUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+
+	if _, ok := twinLunchAdmins[user]; !ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notAnAdmin", messageData{"User": user}))
+		return
+	}
+
+	if _, ok := configuredAdmins[user]; ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "configuredAdmin", messageData{"User": user}))
+		return
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Delete(ctx, adminKey(user))
+	}); err != nil {
+		commandLogger(command).Printf("error deleting admin in datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	delete(twinLunchAdmins, user)
+	recordAudit(auditActionAdminRemoved, command.UserID, user)
+
+	updateHome(user)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "adminRemoved", messageData{"User": user}))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+var (
+	// maxForwardedFileSize is the size in bytes above which files aren't forwarded
+	maxForwardedFileSize = 20 << 20
+
+	// scrubFileNames tells if forwarded files are renamed, it is on unless SCRUB_FILE_NAMES is false
+	scrubFileNames = true
+)
+
+var fileExtRegexp = regexp.MustCompile(`^[a-z0-9]{1,10}$`)
+
+// scrubbedFilename replaces the name of a file forwarded to user, which may reveal the sender, keeping only its extension.
+// The name is kept if scrubbing is turned off.
+func scrubbedFilename(user string, file slackevents.File) string {
+	if !scrubFileNames && file.Name != "" {
+		return file.Name
+	}
+
+	var ext = strings.ToLower(strings.TrimPrefix(path.Ext(file.Name), "."))
+	if !fileExtRegexp.MatchString(ext) {
+		ext = strings.ToLower(file.Filetype)
+	}
+	if !fileExtRegexp.MatchString(ext) {
+		return messageTo(user, "forwardedFileName", nil)
+	}
+	return messageTo(user, "forwardedFileName", nil) + "." + ext
+}
+
+// forwardFile downloads a file sent to the bot and uploads it again to channel, the conversation with user,
+// so that the partner gets a copy owned by the bot, without the sender's name, title or file name.
+func forwardFile(channel string, user string, codename string, file slackevents.File) error {
+	if file.Size > maxForwardedFileSize {
+		_, err := postBotMessage(channel, messageTo(user, "forwardedFileTooLarge", messageData{"Codename": codename}))
+		return err
+	}
+
+	var url = file.URLPrivateDownload
+	if url == "" {
+		url = file.URLPrivate
+	}
+
+	var buf bytes.Buffer
+	if err := slackClient.GetFile(url, &buf); err != nil {
+		return fmt.Errorf("error downloading file: %w", err)
+	}
+
+	if _, err := slackClient.UploadFile(slack.FileUploadParameters{
+		Reader:         &buf,
+		Filename:       scrubbedFilename(user, file),
+		InitialComment: messageTo(user, "forwardedFile", messageData{"Codename": codename}),
+		Channels:       []string{channel},
+	}); err != nil {
+		return fmt.Errorf("error uploading file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestScrubbedFilename(t *testing.T) {
+	var name = message("forwardedFileName", nil)
+
+	var tests = []struct {
+		name  string
+		scrub bool
+		file  slackevents.File
+		want  string
+	}{
+		{"scrubbed", true, slackevents.File{Name: "marie_cv.pdf", Filetype: "pdf"}, name + ".pdf"},
+		{"extension from the file type", true, slackevents.File{Name: "marie_cv", Filetype: "PDF"}, name + ".pdf"},
+		{"no extension", true, slackevents.File{Name: "marie_cv"}, name},
+		{"invalid extension", true, slackevents.File{Name: "marie.c v"}, name},
+		{"kept", false, slackevents.File{Name: "marie_cv.pdf", Filetype: "pdf"}, "marie_cv.pdf"},
+		{"kept without extension", false, slackevents.File{Name: "marie_cv"}, "marie_cv"},
+		{"no name to keep", false, slackevents.File{Filetype: "png"}, name + ".png"},
+	}
+
+	var savedScrub = scrubFileNames
+	t.Cleanup(func() { scrubFileNames = savedScrub })
+
+	for _, test := range tests {
+		scrubFileNames = test.scrub
+		if filename := scrubbedFilename("U1", test.file); filename != test.want {
+			t.Errorf("%s: scrubbedFilename(%q) = %q, want %q", test.name, test.file.Name, filename, test.want)
+		}
+	}
+}
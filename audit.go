@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+	"google.golang.org/api/iterator"
+)
+
+const activityDateLayout = "2006-01-02"
+
+// activityTimeout bounds the export of the activity, from the first query to the end of the upload
+var activityTimeout = 5 * time.Minute
+
+const (
+	auditActionPairAdded    = "pair_added"
+	auditActionPairRemoved  = "pair_removed"
+	auditActionPairQuit     = "pair_quit"
+	auditActionPairsCleared = "pairs_cleared"
+	auditActionPairsSwapped = "pairs_swapped"
+	// auditActionPairsImported has no actor, imports are made with the admin API token
+	auditActionPairsImported = "pairs_imported"
+	auditActionAdminAdded    = "admin_added"
+	auditActionAdminRemoved  = "admin_removed"
+	auditActionBroadcast     = "broadcast"
+)
+
+// TwinLunchAudit records an admin command or a pairing change.
+// It only holds metadata, never the content of relayed messages.
+type TwinLunchAudit struct {
+	At     time.Time
+	Action string
+	Actor  string
+	Users  []string
+}
+
+func recordAudit(action string, actor string, users ...string) {
+	var audit = &TwinLunchAudit{time.Now(), action, actor, users}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, datastore.IncompleteKey("TwinLunchAudit", nil), audit)
+		return err
+	}); err != nil {
+		logger.Printf("error writing audit in datastore: %s", err)
+	}
+}
+
+func recordCommandAudit(command slack.SlashCommand) {
+	var users []string
+	for _, match := range userRegexp.FindAllStringSubmatch(command.Text, -1) {
+		users = append(users, match[1])
+	}
+
+	recordAudit(command.Command, command.UserID, users...)
+}
+
+func handleActivityCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+
+	if len(args) != 2 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "activityUsage", nil))
+		return
+	}
+
+	var from, errFrom = time.ParseInLocation(activityDateLayout, args[0], time.Local)
+	var to, errTo = time.ParseInLocation(activityDateLayout, args[1], time.Local)
+
+	if errFrom != nil || errTo != nil {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "activityInvalidDate", nil))
+		return
+	}
+
+	if to.Before(from) {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "activityInvalidRange", nil))
+		return
+	}
+
+	// the end date is inclusive
+	to = to.AddDate(0, 0, 1)
+
+	// the export may take a while, it mustn't hold the run loop
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+		exportActivity(command, from, to, args[0], args[1])
+	}()
+}
+
+// exportActivity uploads the activity between from and to as a CSV file, reporting through a placeholder message.
+func exportActivity(command slack.SlashCommand, from time.Time, to time.Time, fromArg string, toArg string) {
+	var channel, ts = sendPlaceholderToUser(command.UserID)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), activityTimeout)
+	defer cancel()
+
+	var rows, err = openActivityRows(ctx, from, to)
+	if err != nil {
+		commandLogger(command).Println(err)
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "activityError", nil))
+		return
+	}
+
+	if rows.empty() {
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "activityEmpty", nil))
+		return
+	}
+
+	// the rows are read from datastore before the upload, which would otherwise be bound by slackTimeout
+	var buf bytes.Buffer
+	count, err := rows.writeCSV(&buf)
+	if err != nil {
+		commandLogger(command).Println(err)
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "activityError", nil))
+		return
+	}
+
+	if err := uploadFileToUser(
+		command.UserID,
+		fmt.Sprintf("twinlunch-activity-%s-%s.csv", fromArg, toArg),
+		messageTo(command.UserID, "activityFileTitle", messageData{"From": fromArg, "To": toArg}),
+		&buf,
+	); err != nil {
+		commandLogger(command).Println(err)
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "activityError", nil))
+		return
+	}
+
+	replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "activityDone", messageData{"From": fromArg, "To": toArg, "Count": count}))
+}
+
+// activityRow is a line of the activity export, the audits and the history of pairings are merged by date.
+type activityRow struct {
+	at     time.Time
+	record []string
+}
+
+// activitySource reads the rows of a datastore kind in date order, next is the row to write, nil once it is done.
+type activitySource struct {
+	it   datastoreIterator
+	load func(it datastoreIterator) (*activityRow, error)
+	next *activityRow
+}
+
+func (s *activitySource) advance() error {
+	var row, err = s.load(s.it)
+	if err != nil {
+		return err
+	}
+	s.next = row
+	return nil
+}
+
+type activityRows struct {
+	sources []*activitySource
+}
+
+// openActivityRows runs the queries of the activity between from and to, and reads their first rows.
+// The queries aren't retried, as the rows are read while they are written.
+func openActivityRows(ctx context.Context, from time.Time, to time.Time) (*activityRows, error) {
+	var rows = &activityRows{[]*activitySource{
+		{
+			it:   datastoreClient.Run(ctx, newDatastoreQuery("TwinLunchAudit").Filter("At >=", from).Filter("At <", to).Order("At")),
+			load: loadAuditRow,
+		},
+		{
+			it:   datastoreClient.Run(ctx, newDatastoreQuery("TwinLunchHistory").Filter("CreatedAt >=", from).Filter("CreatedAt <", to).Order("CreatedAt")),
+			load: loadHistoryRow,
+		},
+	}}
+
+	for _, source := range rows.sources {
+		if err := source.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+func (rows *activityRows) empty() bool {
+	for _, source := range rows.sources {
+		if source.next != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeCSV writes the header and the rows in date order, and returns the number of rows written.
+func (rows *activityRows) writeCSV(out io.Writer) (int, error) {
+	var w = csv.NewWriter(out)
+	var count int
+
+	if err := w.Write([]string{"at", "kind", "action", "actor", "users", "round", "messages"}); err != nil {
+		return 0, fmt.Errorf("error writing activity: %w", err)
+	}
+
+	for {
+		var first *activitySource
+		for _, source := range rows.sources {
+			if source.next != nil && (first == nil || source.next.at.Before(first.next.at)) {
+				first = source
+			}
+		}
+		if first == nil {
+			break
+		}
+
+		if err := w.Write(first.next.record); err != nil {
+			return count, fmt.Errorf("error writing activity: %w", err)
+		}
+		count++
+
+		if err := first.advance(); err != nil {
+			return count, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return count, fmt.Errorf("error writing activity: %w", err)
+	}
+
+	return count, nil
+}
+
+func loadAuditRow(it datastoreIterator) (*activityRow, error) {
+	var audit TwinLunchAudit
+	var _, err = it.Next(&audit)
+	if err == iterator.Done {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error listing audits in datastore: %w", err)
+	}
+
+	return &activityRow{audit.At, []string{
+		audit.At.Format(time.RFC3339),
+		"audit",
+		audit.Action,
+		audit.Actor,
+		strings.Join(audit.Users, " "),
+		"",
+		"",
+	}}, nil
+}
+
+func loadHistoryRow(it datastoreIterator) (*activityRow, error) {
+	var history TwinLunchHistory
+	var _, err = it.Next(&history)
+	if err == iterator.Done {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error listing history in datastore: %w", err)
+	}
+
+	return &activityRow{history.CreatedAt, []string{
+		history.CreatedAt.Format(time.RFC3339),
+		"history",
+		"",
+		"",
+		strings.Join(history.Users, " "),
+		strconv.Itoa(history.Round),
+		strconv.Itoa(history.Messages),
+	}}, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+func TestHandleActivityCommand(t *testing.T) {
+	var fs, fd = setupFakes(t)
+	var ctx = context.Background()
+
+	var day = time.Date(2021, 3, 10, 12, 0, 0, 0, time.Local)
+	for _, audit := range []*TwinLunchAudit{
+		{day, auditActionPairAdded, "UADMIN", []string{"U1", "U2"}},
+		{day.Add(2 * time.Hour), auditActionPairRemoved, "UADMIN", []string{"U1", "U2"}},
+		{day.AddDate(0, 1, 0), auditActionPairAdded, "UADMIN", []string{"U3", "U4"}},
+	} {
+		if _, err := fd.Put(ctx, datastore.IncompleteKey("TwinLunchAudit", nil), audit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := fd.Put(ctx, datastore.IDKey("TwinLunchHistory", 1, nil), &TwinLunchHistory{[]string{"U1", "U2"}, day.Add(time.Hour), 2, 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	handleActivityCommand(slack.SlashCommand{Command: "/twinlunch-activity", UserID: "UADMIN", Text: "2021-03-01 2021-03-31"})
+	deliveries.Wait()
+
+	var messages = fs.messagesTo(dmChannel("UADMIN"))
+	if len(messages) != 2 {
+		t.Fatalf("messages = %q, want the placeholder and the file", messages)
+	}
+
+	var lines = strings.Split(strings.TrimSpace(messages[1]), "\n")
+	var want = []string{
+		"twinlunch-activity-2021-03-01-2021-03-31.csv",
+		"at,kind,action,actor,users,round,messages",
+		day.Format(time.RFC3339) + ",audit,pair_added,UADMIN,U1 U2,,",
+		day.Add(time.Hour).Format(time.RFC3339) + ",history,,,U1 U2,2,5",
+		day.Add(2*time.Hour).Format(time.RFC3339) + ",audit,pair_removed,UADMIN,U1 U2,,",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("uploaded file =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(fs.updated) != 1 || !strings.Contains(fs.updated[0].Text, message("activityDone", messageData{"From": "2021-03-01", "To": "2021-03-31", "Count": 3})) {
+		t.Errorf("placeholder updates = %q, want the activity done message", fs.updated)
+	}
+}
+
+func TestRemoveGroupAudit(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	if _, err := createTwinLunch([]string{"U1", "U2", "U3"}, 0, "UADMIN"); err != nil {
+		t.Fatal(err)
+	}
+
+	handleRemoveCommand(slack.SlashCommand{Command: "/twinlunch-remove", UserID: "UADMIN", Text: "<@U1> <@U3>"})
+	deliveries.Wait()
+
+	var audits []*TwinLunchAudit
+	if _, err := fd.GetAll(context.Background(), newDatastoreQuery("TwinLunchAudit").Filter("Action =", auditActionPairRemoved), &audits); err != nil {
+		t.Fatal(err)
+	}
+	if len(audits) != 1 || !reflect.DeepEqual(audits[0].Users, []string{"U1", "U2", "U3"}) {
+		t.Errorf("removal audits = %+v, want one listing every member of the group", audits)
+	}
+}
+
+func TestDryRunWritesNothing(t *testing.T) {
+	var tests = []struct {
+		name       string
+		command    string
+		text       string
+		wantWrites bool
+	}{
+		{"add", "/twinlunch-add", "<@U1> <@U2> --dry-run", false},
+		{"import", "/twinlunch-import", "<@U1> <@U2>\n<@U3> <@U4>\npreview", false},
+		{"random", "/twinlunch-random", "--dry-run <@U1> <@U2> <@U3> <@U4>", false},
+		{"not a dry run command", "/twinlunch-broadcast", "see you at the preview", true},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var _, fd = setupFakes(t)
+			twinLunchAdmins["UADMIN"] = struct{}{}
+
+			handleCommand(slack.SlashCommand{Command: test.command, UserID: "UADMIN", Text: test.text})
+			deliveries.Wait()
+
+			var writes int
+			for _, method := range []string{"AllocateIDs", "Put", "PutMulti", "Delete", "DeleteMulti", "RunInTransaction"} {
+				writes += fd.callCount(method)
+			}
+			if (writes != 0) != test.wantWrites {
+				t.Errorf("datastore writes = %d, want writes %t", writes, test.wantWrites)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// broadcastInterval spaces the announcement messages, so that large rosters don't hit the slack rate limits
+var broadcastInterval = time.Second
+
+// handleBroadcastCommand sends an announcement to every user who currently has a twin lunch.
+func handleBroadcastCommand(command slack.SlashCommand) {
+	var text = strings.TrimSpace(command.Text)
+	if text == "" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "broadcastUsage", nil))
+		return
+	}
+
+	var users []string
+	var listed = make(map[string]struct{})
+	for _, twinLunch := range twinLunches.List() {
+		for _, user := range twinLunch.Members() {
+			if _, ok := listed[user]; ok {
+				continue
+			}
+			listed[user] = struct{}{}
+			users = append(users, user)
+		}
+	}
+
+	if len(users) == 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "broadcastNobody", nil))
+		return
+	}
+
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+
+		var ticker = time.NewTicker(broadcastInterval)
+		defer ticker.Stop()
+
+		for i, user := range users {
+			if i != 0 {
+				<-ticker.C
+			}
+			sendBotMessageToUser(user, messageTo(user, "broadcast", messageData{"Text": text}))
+		}
+	}()
+
+	recordAudit(auditActionBroadcast, command.UserID, users...)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "broadcastSent", messageData{"Count": len(users)}))
+}
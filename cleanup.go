@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+// cleanupOnRemove deletes the copies of the relayed messages when a twin lunch ends, the members' own messages can't be deleted by the bot
+var cleanupOnRemove bool
+
+// cleanupRelayedMessages forgets the messages relayed during a twin lunch which ended, the links between the messages
+// and their copies are only needed for the reactions and the edits during the twin lunch.
+// With deleteCopies the copies relayed to the members are deleted first, and the members are told how many were deleted.
+// This is queued after the messages already being sent, before the end of the twin lunch is announced.
+func cleanupRelayedMessages(twinLunch *TwinLunch, deleteCopies bool) {
+	if twinLunch.Key == nil {
+		return
+	}
+
+	var twinLunchID = twinLunch.Key.ID
+	var members = twinLunch.Members()
+
+	// the links are forgotten once the queues of all the members are past the end of the twin lunch
+	var remaining = int32(len(members))
+	var done = func() {
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			if err := forgetRelayedMessages(twinLunchID); err != nil {
+				logger.Println(err)
+			}
+		}
+	}
+
+	for _, user := range members {
+		var user = user
+
+		var channel, err = getChannelForUser(user)
+		if err != nil {
+			logger.Println(err)
+			done()
+			continue
+		}
+
+		enqueueDelivery(channel, delivery{user: user, logger: logger.With("cleanup", twinLunchID, "to", logUser(user)), dropped: done, send: func() error {
+			defer done()
+
+			if !deleteCopies {
+				return nil
+			}
+
+			var deleted, err = deleteRelayedCopies(twinLunchID, channel)
+			if deleted != 0 {
+				if _, err := postBotMessage(channel, messageTo(user, "relayedCopiesDeleted", messageData{"Count": deleted})); err != nil {
+					logger.Println(err)
+				}
+			}
+			return err
+		}})
+	}
+}
+
+// forgetRelayedMessages deletes the relayed messages and copies recorded during a twin lunch.
+func forgetRelayedMessages(twinLunchID int64) error {
+	for _, kind := range []string{"TwinLunchRelayedMessage", "TwinLunchRelayedCopy"} {
+		var keys []*datastore.Key
+
+		if err := withDatastore(context.Background(), func(ctx context.Context) error {
+			var err error
+			keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery(kind).Filter("TwinLunchID =", twinLunchID).KeysOnly(), nil)
+			return err
+		}); err != nil {
+			return fmt.Errorf("error reading relayed messages from datastore: %w", err)
+		}
+
+		for len(keys) != 0 {
+			var batch = keys
+			if len(batch) > maxPutMulti {
+				batch = batch[:maxPutMulti]
+			}
+
+			if err := withDatastore(context.Background(), func(ctx context.Context) error {
+				return datastoreClient.DeleteMulti(ctx, batch)
+			}); err != nil {
+				return fmt.Errorf("error deleting relayed messages in datastore: %w", err)
+			}
+
+			keys = keys[len(batch):]
+		}
+	}
+
+	return nil
+}
+
+// deleteRelayedCopies deletes the messages the bot relayed to channel during the twin lunch, a message which can't
+// be deleted is skipped, so that one error doesn't keep the others.
+func deleteRelayedCopies(twinLunchID int64, channel string) (int, error) {
+	var copies []*TwinLunchRelayedCopy
+	var keys []*datastore.Key
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		copies = nil
+		var err error
+		keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchRelayedCopy").Filter("TwinLunchID =", twinLunchID), &copies)
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("error reading relayed copies from datastore: %w", err)
+	}
+
+	var deleted []*datastore.Key
+
+	for i, relayedCopy := range copies {
+		// the copies of the other members are deleted from their own queue
+		if keys[i].Name != channel {
+			continue
+		}
+
+		if _, _, err := slackClient.DeleteMessage(channel, relayedCopy.TS); err != nil && !isMessageNotFoundError(err) {
+			logger.Printf("error deleting relayed message: %s", err)
+			continue
+		}
+		deleted = append(deleted, keys[i])
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.DeleteMulti(ctx, deleted)
+	}); err != nil {
+		return len(deleted), fmt.Errorf("error deleting relayed copies in datastore: %w", err)
+	}
+
+	return len(deleted), nil
+}
+
+// isMessageNotFoundError tells if the message was already deleted, by its author or in the Slack client.
+func isMessageNotFoundError(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	return errors.As(err, &slackErr) && slackErr.Err == "message_not_found"
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const configDateLayout = "2006-01-02"
+
+func getEnvInt(name string, value int) int {
+	if s := os.Getenv(name); s != "" {
+		var err error
+		if value, err = strconv.Atoi(s); err != nil {
+			logger.Fatalf("invalid %s: %s", name, err)
+		}
+	}
+	return value
+}
+
+func getEnvDuration(name string, value time.Duration) time.Duration {
+	if s := os.Getenv(name); s != "" {
+		var err error
+		if value, err = time.ParseDuration(s); err != nil {
+			logger.Fatalf("invalid %s: %s", name, err)
+		}
+	}
+	return value
+}
+
+func getEnvDate(name string) time.Time {
+	var s = os.Getenv(name)
+	if s == "" {
+		return time.Time{}
+	}
+
+	var value, err = time.ParseInLocation(configDateLayout, s, time.Local)
+	if err != nil {
+		logger.Fatalf("invalid %s: %s", name, err)
+	}
+	return value
+}
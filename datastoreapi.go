@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"cloud.google.com/go/datastore"
+)
+
+// datastoreAPI is the part of the datastore client used by the bot, it allows replacing the datastore with a fake one.
+type datastoreAPI interface {
+	AllocateIDs(ctx context.Context, keys []*datastore.Key) ([]*datastore.Key, error)
+
+	Get(ctx context.Context, key *datastore.Key, dst interface{}) error
+	GetAll(ctx context.Context, q *datastoreQuery, dst interface{}) ([]*datastore.Key, error)
+	Run(ctx context.Context, q *datastoreQuery) datastoreIterator
+
+	Put(ctx context.Context, key *datastore.Key, src interface{}) (*datastore.Key, error)
+	PutMulti(ctx context.Context, keys []*datastore.Key, src interface{}) ([]*datastore.Key, error)
+	Delete(ctx context.Context, key *datastore.Key) error
+	DeleteMulti(ctx context.Context, keys []*datastore.Key) error
+
+	RunInTransaction(ctx context.Context, f func(tx datastoreTransaction) error) error
+
+	Close() error
+}
+
+// datastoreIterator is the result of a query run with datastoreAPI.Run.
+type datastoreIterator interface {
+	Next(dst interface{}) (*datastore.Key, error)
+}
+
+// datastoreTransaction is the part of datastore.Transaction used by the bot, the queries run in it are ancestor queries.
+type datastoreTransaction interface {
+	Get(key *datastore.Key, dst interface{}) error
+	Run(ctx context.Context, q *datastoreQuery) datastoreIterator
+
+	Put(key *datastore.Key, src interface{}) (*datastore.PendingKey, error)
+	PutMulti(keys []*datastore.Key, src interface{}) ([]*datastore.PendingKey, error)
+	Delete(key *datastore.Key) error
+	DeleteMulti(keys []*datastore.Key) error
+}
+
+// datastoreQuery is a datastore query which can be read by a fake datastore, with the parts of datastore.Query used by the bot.
+// Like datastore.Query, its methods return a modified copy.
+type datastoreQuery struct {
+	kind     string
+	ancestor *datastore.Key
+	filters  []datastoreFilter
+	// orders are field names, prefixed with - for a descending order
+	orders   []string
+	limit    int
+	keysOnly bool
+}
+
+// datastoreFilter compares field with value, op is one of <, <=, =, >= and >.
+type datastoreFilter struct {
+	field string
+	op    string
+	value interface{}
+}
+
+func newDatastoreQuery(kind string) *datastoreQuery {
+	return &datastoreQuery{kind: kind}
+}
+
+func (q *datastoreQuery) clone() *datastoreQuery {
+	var c = *q
+	c.filters = append([]datastoreFilter(nil), q.filters...)
+	c.orders = append([]string(nil), q.orders...)
+	return &c
+}
+
+func (q *datastoreQuery) Ancestor(ancestor *datastore.Key) *datastoreQuery {
+	var c = q.clone()
+	c.ancestor = ancestor
+	return c
+}
+
+// Filter adds a filter like datastore.Query.Filter, filterStr is a field name followed by an operator, like "Round >=".
+func (q *datastoreQuery) Filter(filterStr string, value interface{}) *datastoreQuery {
+	var c = q.clone()
+	var field, op = strings.TrimSpace(filterStr), ""
+	if i := strings.LastIndexByte(field, ' '); i != -1 {
+		field, op = strings.TrimSpace(field[:i]), field[i+1:]
+	}
+	c.filters = append(c.filters, datastoreFilter{field, op, value})
+	return c
+}
+
+func (q *datastoreQuery) Order(fieldName string) *datastoreQuery {
+	var c = q.clone()
+	c.orders = append(c.orders, fieldName)
+	return c
+}
+
+func (q *datastoreQuery) Limit(limit int) *datastoreQuery {
+	var c = q.clone()
+	c.limit = limit
+	return c
+}
+
+func (q *datastoreQuery) KeysOnly() *datastoreQuery {
+	var c = q.clone()
+	c.keysOnly = true
+	return c
+}
+
+// query returns the datastore.Query run by Cloud Datastore.
+func (q *datastoreQuery) query() *datastore.Query {
+	var query = datastore.NewQuery(q.kind)
+	if q.ancestor != nil {
+		query = query.Ancestor(q.ancestor)
+	}
+	for _, filter := range q.filters {
+		query = query.Filter(filter.field+" "+filter.op, filter.value)
+	}
+	for _, order := range q.orders {
+		query = query.Order(order)
+	}
+	if q.limit > 0 {
+		query = query.Limit(q.limit)
+	}
+	if q.keysOnly {
+		query = query.KeysOnly()
+	}
+	return query
+}
+
+// cloudDatastore is the datastoreAPI of Cloud Datastore.
+type cloudDatastore struct {
+	*datastore.Client
+}
+
+func (c cloudDatastore) GetAll(ctx context.Context, q *datastoreQuery, dst interface{}) ([]*datastore.Key, error) {
+	return c.Client.GetAll(ctx, q.query(), dst)
+}
+
+func (c cloudDatastore) Run(ctx context.Context, q *datastoreQuery) datastoreIterator {
+	return c.Client.Run(ctx, q.query())
+}
+
+func (c cloudDatastore) RunInTransaction(ctx context.Context, f func(tx datastoreTransaction) error) error {
+	var _, err = c.Client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		return f(cloudTransaction{tx, c.Client})
+	})
+	return err
+}
+
+type cloudTransaction struct {
+	*datastore.Transaction
+	client *datastore.Client
+}
+
+func (t cloudTransaction) Run(ctx context.Context, q *datastoreQuery) datastoreIterator {
+	return t.client.Run(ctx, q.query().Transaction(t.Transaction))
+}
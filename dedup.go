@@ -0,0 +1,40 @@
+package main
+
+import "time"
+
+// eventDedupWindow covers the Slack retry schedule, events seen again within it are dropped
+var eventDedupWindow = 5 * time.Minute
+
+type seenEvent struct {
+	id string
+	at time.Time
+}
+
+// eventDeduplicator is a time windowed set of event IDs, it is only accessed from receiveEvents.
+type eventDeduplicator struct {
+	ids   map[string]struct{}
+	order []seenEvent
+}
+
+func newEventDeduplicator() *eventDeduplicator {
+	return &eventDeduplicator{ids: make(map[string]struct{})}
+}
+
+// seen records id and tells whether it was already seen in the window.
+func (d *eventDeduplicator) seen(id string) bool {
+	var now = time.Now()
+
+	for len(d.order) != 0 && now.Sub(d.order[0].at) > eventDedupWindow {
+		delete(d.ids, d.order[0].id)
+		d.order = d.order[1:]
+	}
+
+	if _, ok := d.ids[id]; ok {
+		return true
+	}
+
+	d.ids[id] = struct{}{}
+	d.order = append(d.order, seenEvent{id, now})
+
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	deliveryDelay = time.Second
+
+	// deliveryQueues holds a queue per recipient channel, so that messages to a channel are sent in the order they
+	// were enqueued, each channel independently of the others
+	deliveryQueuesMu sync.Mutex
+	deliveryQueues   = make(map[string]*deliveryQueue)
+)
+
+// delivery is a message waiting to be sent, user is its recipient if it is sent in a direct message.
+// dropped is optional, it is called instead of send when the delivery is dropped.
+// logger is optional too, it logs the send error with the context of the message.
+type delivery struct {
+	user    string
+	at      time.Time
+	send    func() error
+	dropped func()
+	logger  *fieldLogger
+}
+
+type deliveryQueue struct {
+	pending []delivery
+}
+
+// enqueueDelivery sends a message after deliveryDelay, after the previous messages to the same channel.
+func enqueueDelivery(channel string, d delivery) {
+	deliveryQueuesMu.Lock()
+	defer deliveryQueuesMu.Unlock()
+
+	var queue, running = deliveryQueues[channel]
+	if !running {
+		queue = &deliveryQueue{}
+		deliveryQueues[channel] = queue
+	}
+
+	d.at = time.Now().Add(deliveryDelay)
+
+	deliveries.Add(1)
+	queue.pending = append(queue.pending, d)
+
+	if !running {
+		go drainDeliveryQueue(channel, queue)
+	}
+}
+
+func drainDeliveryQueue(channel string, queue *deliveryQueue) {
+	for {
+		deliveryQueuesMu.Lock()
+		if len(queue.pending) == 0 {
+			delete(deliveryQueues, channel)
+			deliveryQueuesMu.Unlock()
+			return
+		}
+		var next = queue.pending[0]
+		queue.pending = queue.pending[1:]
+		deliveryQueuesMu.Unlock()
+
+		time.Sleep(time.Until(next.at))
+
+		if err := next.send(); err != nil {
+			var deliveryLogger = next.logger
+			if deliveryLogger == nil {
+				deliveryLogger = logger.With("to", logUser(next.user))
+			}
+			deliveryLogger.Println(err)
+		}
+		deliveries.Done()
+	}
+}
+
+// dropDeliveries drops the messages to users which aren't sent yet, a message being sent can't be dropped.
+func dropDeliveries(users ...string) {
+	var dropped []delivery
+
+	deliveryQueuesMu.Lock()
+	for _, queue := range deliveryQueues {
+		var kept = queue.pending[:0]
+		for _, d := range queue.pending {
+			if d.user != "" && containsUser(users, d.user) {
+				dropped = append(dropped, d)
+			} else {
+				kept = append(kept, d)
+			}
+		}
+		queue.pending = kept
+	}
+	deliveryQueuesMu.Unlock()
+
+	for _, d := range dropped {
+		if d.dropped != nil {
+			d.dropped()
+		}
+		deliveries.Done()
+	}
+
+	if len(dropped) != 0 {
+		logger.Printf("dropped %d pending messages", len(dropped))
+	}
+}
+
+func containsUser(users []string, user string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// TwinLunchRelayedCopy is the copy of a message in a partner's conversation, keyed by the partner's channel
+// under the original message key, so that a message relayed to a group has one copy per partner.
+type TwinLunchRelayedCopy struct {
+	TwinLunchID int64
+	TS          string `datastore:",noindex"`
+	// Disclaimed tells if the relay disclaimer was added to the copy
+	Disclaimed bool `datastore:",noindex"`
+	CreatedAt  time.Time
+}
+
+func relayedCopyKey(channel string, ts string, copyChannel string) *datastore.Key {
+	return datastore.NameKey("TwinLunchRelayedCopy", copyChannel, relayedMessageKey(channel, ts))
+}
+
+func recordRelayedCopy(twinLunchID int64, channel string, ts string, copyChannel string, copyTS string, disclaimed bool) {
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, relayedCopyKey(channel, ts, copyChannel), &TwinLunchRelayedCopy{twinLunchID, copyTS, disclaimed, time.Now()})
+		return err
+	}); err != nil {
+		logger.Printf("error writing relayed copy in datastore: %s", err)
+	}
+}
+
+// getRelayedCopy returns nil if the message wasn't relayed to copyChannel during the twin lunch.
+func getRelayedCopy(twinLunchID int64, channel string, ts string, copyChannel string) (*TwinLunchRelayedCopy, error) {
+	var relayedCopy TwinLunchRelayedCopy
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Get(ctx, relayedCopyKey(channel, ts, copyChannel), &relayedCopy)
+	}); err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading relayed copy from datastore: %w", err)
+	}
+
+	// the message may come from a previous pairing
+	if relayedCopy.TwinLunchID != twinLunchID {
+		return nil, nil
+	}
+
+	return &relayedCopy, nil
+}
+
+// handleMessageChanged updates the partners' copies of an edited message, edits of messages which weren't relayed are ignored.
+// The copies are looked up when the update is sent, after the copies still in the delivery queue are posted.
+func handleMessageChanged(event *slackevents.MessageEvent) {
+	var edited = event.Message
+
+	var twinLunch, ok = twinLunches.Get(edited.User)
+	if !ok || twinLunch.Key == nil || edited.Text == "" {
+		return
+	}
+
+	for _, partner := range twinLunch.Partners(edited.User) {
+		var relayed = wrapRelayedText(neutralizeMentions(partner, edited.Text))
+		var relayLogger = logger.With("relay", "edit", "from", logUser(edited.User), "to", logUser(partner))
+
+		var channel, err = getChannelForUser(partner)
+		if err != nil {
+			relayLogger.Println(err)
+			continue
+		}
+
+		var partner = partner
+		enqueueDelivery(channel, delivery{user: partner, logger: relayLogger, send: func() error {
+			var relayedCopy, err = getRelayedCopy(twinLunch.Key.ID, event.Channel, edited.TimeStamp, channel)
+			if err != nil || relayedCopy == nil {
+				return err
+			}
+
+			var text = relayed
+			if relayedCopy.Disclaimed {
+				text += relayDisclaimerFooter(partner)
+			}
+
+			if _, _, _, err := slackClient.UpdateMessage(channel, relayedCopy.TS, slack.MsgOptionText(tagOrigin("RELAY", channel, partner, text), false)); err != nil {
+				return fmt.Errorf("error updating relayed message: %w", err)
+			}
+			return nil
+		}})
+	}
+}
+
+// handleMessageDeleted deletes the partners' copies of a deleted message, messages which weren't relayed are ignored.
+func handleMessageDeleted(event *slackevents.MessageEvent) {
+	var deleted = event.PreviousMessage
+
+	var twinLunch, ok = twinLunches.Get(deleted.User)
+	if !ok || twinLunch.Key == nil {
+		return
+	}
+
+	for _, partner := range twinLunch.Partners(deleted.User) {
+		var relayLogger = logger.With("relay", "delete", "from", logUser(deleted.User), "to", logUser(partner))
+
+		var channel, err = getChannelForUser(partner)
+		if err != nil {
+			relayLogger.Println(err)
+			continue
+		}
+
+		enqueueDelivery(channel, delivery{user: partner, logger: relayLogger, send: func() error {
+			var relayedCopy, err = getRelayedCopy(twinLunch.Key.ID, event.Channel, deleted.TimeStamp, channel)
+			if err != nil || relayedCopy == nil {
+				return err
+			}
+
+			if _, _, err := slackClient.DeleteMessage(channel, relayedCopy.TS); err != nil {
+				return fmt.Errorf("error deleting relayed message: %w", err)
+			}
+
+			if err := withDatastore(context.Background(), func(ctx context.Context) error {
+				return datastoreClient.Delete(ctx, relayedCopyKey(event.Channel, deleted.TimeStamp, channel))
+			}); err != nil {
+				return fmt.Errorf("error deleting relayed copy in datastore: %w", err)
+			}
+			return nil
+		}})
+	}
+}
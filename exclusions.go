@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+const exclusionsPageSize = 20
+
+// TwinLunchExclusion prevents two users from being paired together.
+type TwinLunchExclusion struct {
+	User1, User2 string
+	Reason       string `datastore:",noindex"`
+	CreatedBy    string
+	CreatedAt    time.Time
+}
+
+func exclusionKey(user1 string, user2 string) *datastore.Key {
+	if user2 < user1 {
+		user1, user2 = user2, user1
+	}
+	return datastore.NameKey("TwinLunchExclusion", user1+":"+user2, nil)
+}
+
+func getExclusion(user1 string, user2 string) (*TwinLunchExclusion, error) {
+	var exclusion TwinLunchExclusion
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Get(ctx, exclusionKey(user1, user2), &exclusion)
+	}); errors.Is(err, datastore.ErrNoSuchEntity) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading exclusion from datastore: %w", err)
+	}
+
+	return &exclusion, nil
+}
+
+func handleExcludeCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 2 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "excludeUsage", nil))
+		return
+	}
+
+	var user1, user2 = matches[0][1], matches[1][1]
+
+	if user1 == user2 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "excludeSameUser", nil))
+		return
+	}
+
+	var exclusion = &TwinLunchExclusion{
+		User1:     user1,
+		User2:     user2,
+		Reason:    strings.Join(strings.Fields(userRegexp.ReplaceAllString(command.Text, "")), " "),
+		CreatedBy: command.UserID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, exclusionKey(user1, user2), exclusion)
+		return err
+	}); err != nil {
+		commandLogger(command).Printf("error writing exclusion in datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "excluded", messageData{"User1": user1, "User2": user2}))
+}
+
+func handleExclusionsCommand(command slack.SlashCommand) {
+	var page = 1
+	if text := strings.TrimSpace(command.Text); text != "" {
+		var err error
+		if page, err = strconv.Atoi(text); err != nil || page < 1 {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "exclusionsInvalidPage", nil))
+			return
+		}
+	}
+
+	var exclusions []*TwinLunchExclusion
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		exclusions = nil
+		var _, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchExclusion").Order("CreatedAt"), &exclusions)
+		return err
+	}); err != nil {
+		commandLogger(command).Printf("error reading exclusions from datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	if len(exclusions) == 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "noExclusions", nil))
+		return
+	}
+
+	var pages = (len(exclusions) + exclusionsPageSize - 1) / exclusionsPageSize
+	if page > pages {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "exclusionsPageOutOfRange", messageData{"Pages": pages}))
+		return
+	}
+
+	var end = page * exclusionsPageSize
+	if end > len(exclusions) {
+		end = len(exclusions)
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "exclusions", messageData{
+		"Page":       page,
+		"Pages":      pages,
+		"Exclusions": exclusions[(page-1)*exclusionsPageSize : end],
+	}))
+}
+
+func getExcludedPairs() (map[string]struct{}, error) {
+	var keys []*datastore.Key
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var err error
+		keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchExclusion").KeysOnly(), nil)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("error reading exclusions from datastore: %w", err)
+	}
+
+	var excluded = make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		excluded[key.Name] = struct{}{}
+	}
+
+	return excluded, nil
+}
+
+func isExcluded(excluded map[string]struct{}, user1 string, user2 string) bool {
+	var _, ok = excluded[exclusionKey(user1, user2).Name]
+	return ok
+}
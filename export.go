@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/api/iterator"
+)
+
+const adminAPITokenHeader = "X-Admin-Token"
+
+var (
+	// adminAPI enables the export and import endpoints, authenticated with the ADMIN_API_TOKEN secret
+	adminAPI        bool
+	adminAPITimeout = 30 * time.Second
+
+	adminAPITokenMu sync.Mutex
+	adminAPIToken   string
+
+	// adminRequests hands the export and import requests over to the run loop
+	adminRequests = make(chan adminRequest)
+)
+
+// exportedTwinLunch is the JSON form of a pairing, ID is its datastore key ID.
+type exportedTwinLunch struct {
+	ID int64 `json:"id,omitempty"`
+	*TwinLunch
+}
+
+// adminRequest is an export if imported is nil, an import otherwise.
+type adminRequest struct {
+	imported []*TwinLunch
+	reply    chan<- adminResponse
+}
+
+type adminResponse struct {
+	twinLunches []*TwinLunch
+	err         error
+}
+
+func registerAdminAPI() {
+	http.HandleFunc("/admin/export", handleExportRequest)
+	http.HandleFunc("/admin/import", handleImportRequest)
+}
+
+// checkAdminAPIToken reads the token from secret manager on first use, so that it doesn't delay startup.
+// The lock isn't held while reading the secret, concurrent first requests may each read it.
+func checkAdminAPIToken(w http.ResponseWriter, r *http.Request) bool {
+	adminAPITokenMu.Lock()
+	var token = adminAPIToken
+	adminAPITokenMu.Unlock()
+
+	if token == "" {
+		var secrets, err = getSecrets(r.Context(), "ADMIN_API_TOKEN")
+		if err != nil {
+			logger.Println(err)
+			http.Error(w, "error reading admin token", http.StatusInternalServerError)
+			return false
+		}
+		token = secrets["ADMIN_API_TOKEN"]
+
+		adminAPITokenMu.Lock()
+		adminAPIToken = token
+		adminAPITokenMu.Unlock()
+	}
+
+	var given = r.Header.Get(adminAPITokenHeader)
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		http.Error(w, "invalid admin token", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func handleExportRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkAdminAPIToken(w, r) {
+		return
+	}
+
+	var response, ok = sendAdminRequest(w, r, nil)
+	if !ok {
+		return
+	}
+
+	var exported = make([]exportedTwinLunch, 0, len(response.twinLunches))
+	for _, twinLunch := range response.twinLunches {
+		exported = append(exported, exportedTwinLunch{twinLunch.Key.ID, twinLunch})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(exported); err != nil {
+		logger.Printf("error writing export: %s", err)
+	}
+}
+
+// handleImportRequest replaces all the pairings with the ones of the request body.
+// The ongoing pairings keep the key ID they were exported with, so that their history still matches.
+func handleImportRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkAdminAPIToken(w, r) {
+		return
+	}
+
+	var exported []exportedTwinLunch
+	if err := json.NewDecoder(r.Body).Decode(&exported); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var imported = make([]*TwinLunch, 0, len(exported))
+	var errs []string
+	var entries = make(map[string]int)
+	var ids = make(map[int64]int)
+
+	for i, entry := range exported {
+		if entry.TwinLunch == nil || entry.User1 == "" || entry.User2 == "" {
+			errs = append(errs, fmt.Sprintf("entry %d: missing users", i))
+			continue
+		}
+		if count := len(entry.Members()); count > groupSize {
+			errs = append(errs, fmt.Sprintf("entry %d: %d users, at most %d are paired together", i, count, groupSize))
+		}
+		if entry.ID != 0 {
+			if first, ok := ids[entry.ID]; ok {
+				errs = append(errs, fmt.Sprintf("entry %d: id %d already used by entry %d", i, entry.ID, first))
+			}
+			ids[entry.ID] = i
+		}
+		for _, user := range entry.Members() {
+			if first, ok := entries[user]; ok {
+				if first == i {
+					errs = append(errs, fmt.Sprintf("entry %d: user %s is paired with themselves", i, user))
+				} else {
+					errs = append(errs, fmt.Sprintf("entry %d: user %s is already in entry %d", i, user, first))
+				}
+				continue
+			}
+			entries[user] = i
+		}
+
+		if entry.ID != 0 {
+			entry.Key = datastore.IDKey("TwinLunch", entry.ID, twinLunchListKey)
+		} else {
+			entry.Key = nil
+		}
+		imported = append(imported, entry.TwinLunch)
+	}
+
+	if len(errs) != 0 {
+		http.Error(w, strings.Join(errs, "\n"), http.StatusBadRequest)
+		return
+	}
+
+	var response, ok = sendAdminRequest(w, r, imported)
+	if !ok {
+		return
+	}
+
+	fmt.Fprintf(w, "imported %d twin lunches, replacing %d\n", len(imported), len(response.twinLunches))
+}
+
+// sendAdminRequest waits for the run loop to handle the request, imported is nil for exports.
+func sendAdminRequest(w http.ResponseWriter, r *http.Request, imported []*TwinLunch) (adminResponse, bool) {
+	var ctx, cancel = context.WithTimeout(r.Context(), adminAPITimeout)
+	defer cancel()
+
+	var reply = make(chan adminResponse, 1)
+
+	select {
+	case adminRequests <- adminRequest{imported, reply}:
+	case <-ctx.Done():
+		http.Error(w, "bot not started", http.StatusServiceUnavailable)
+		return adminResponse{}, false
+	}
+
+	var response = <-reply
+	if response.err != nil {
+		logger.Println(response.err)
+		http.Error(w, "datastore error", http.StatusInternalServerError)
+		return adminResponse{}, false
+	}
+
+	return response, true
+}
+
+func handleAdminRequest(request adminRequest) {
+	if request.imported == nil {
+		// the pairings are copied, as the run loop keeps modifying them while the export is encoded
+		var exported []*TwinLunch
+		for _, twinLunch := range twinLunches.List() {
+			var copied = *twinLunch
+			exported = append(exported, &copied)
+		}
+		request.reply <- adminResponse{twinLunches: exported}
+		return
+	}
+
+	var removed, err = replaceTwinLunches(request.imported)
+	request.reply <- adminResponse{removed, err}
+}
+
+// replaceTwinLunches deletes all the pairings and saves the imported ones in a single transaction, then loads them in memory.
+// Nobody is notified, neither of the removed pairings nor of the imported ones.
+func replaceTwinLunches(imported []*TwinLunch) ([]*TwinLunch, error) {
+	// the IDs of the other pairings may not have been allocated by datastore, which could allocate them again later
+	var current = make(map[int64]struct{})
+	for _, twinLunch := range twinLunches.List() {
+		if twinLunch.Key != nil {
+			current[twinLunch.Key.ID] = struct{}{}
+		}
+	}
+	for _, twinLunch := range imported {
+		if twinLunch.Key == nil {
+			continue
+		}
+		if _, ok := current[twinLunch.Key.ID]; !ok {
+			twinLunch.Key = nil
+		}
+	}
+
+	var missing int
+	for _, twinLunch := range imported {
+		if twinLunch.Key == nil {
+			missing++
+		}
+	}
+
+	var allocated, err = allocateKeys("TwinLunch", twinLunchListKey, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys = make([]*datastore.Key, len(imported))
+	var kept = make(map[int64]struct{}, len(imported))
+	for i, twinLunch := range imported {
+		if keys[i] = twinLunch.Key; keys[i] == nil {
+			keys[i], allocated = allocated[0], allocated[1:]
+		}
+		kept[keys[i].ID] = struct{}{}
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return runInTransaction(ctx, func(tx datastoreTransaction) error {
+			var it = tx.Run(ctx, newDatastoreQuery("TwinLunch").Ancestor(twinLunchListKey).KeysOnly())
+			var deleted []*datastore.Key
+
+			for {
+				var k, err = it.Next(nil)
+				if err == iterator.Done {
+					break
+				} else if err != nil {
+					return fmt.Errorf("error listing keys in datastore: %w", err)
+				}
+				// an entity can't be deleted and written in the same transaction
+				if _, ok := kept[k.ID]; !ok {
+					deleted = append(deleted, k)
+				}
+			}
+
+			if err := tx.DeleteMulti(deleted); err != nil {
+				return fmt.Errorf("error deleting keys in datastore: %w", err)
+			}
+			if _, err := tx.PutMulti(keys, imported); err != nil {
+				return fmt.Errorf("error writing keys in datastore: %w", err)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	for i, twinLunch := range imported {
+		twinLunch.Key = keys[i]
+	}
+
+	var removed = twinLunches.Clear()
+	for _, twinLunch := range imported {
+		twinLunches.Pair(twinLunch)
+	}
+	updateTopic()
+
+	// the pairings imported with their ID keep their relayed messages
+	for _, twinLunch := range removed {
+		if twinLunch.Key == nil {
+			continue
+		}
+		if _, ok := kept[twinLunch.Key.ID]; !ok {
+			cleanupRelayedMessages(twinLunch, false)
+		}
+	}
+
+	var users []string
+	for _, twinLunch := range removed {
+		users = append(users, twinLunch.Members()...)
+	}
+	for _, twinLunch := range imported {
+		users = append(users, twinLunch.Members()...)
+	}
+	updateHome(users...)
+
+	recordAudit(auditActionPairsImported, "", users...)
+
+	return removed, nil
+}
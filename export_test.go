@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// postImport posts body to the import endpoint, the run loop is stood in for by handling a single admin request.
+func postImport(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var savedToken = adminAPIToken
+	adminAPIToken = "secret"
+	t.Cleanup(func() { adminAPIToken = savedToken })
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case request := <-adminRequests:
+			handleAdminRequest(request)
+		case <-ctx.Done():
+		}
+	}()
+
+	var request = httptest.NewRequest(http.MethodPost, "/admin/import", strings.NewReader(body))
+	request.Header.Set(adminAPITokenHeader, "secret")
+	var recorder = httptest.NewRecorder()
+	handleImportRequest(recorder, request)
+
+	cancel()
+	<-done
+	return recorder
+}
+
+func TestImportKeys(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	var current, err = createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Wait()
+
+	// 1000 isn't the ID of an ongoing pairing, datastore may allocate it later
+	var response = postImport(t, fmt.Sprintf(`[{"id":%d,"User1":"U1","User2":"U2"},{"id":1000,"User1":"U3","User2":"U4"}]`, current.Key.ID))
+	if response.Code != http.StatusOK {
+		t.Fatalf("import status = %d %q, want OK", response.Code, response.Body)
+	}
+
+	var stored []*TwinLunch
+	if _, err := fd.GetAll(context.Background(), newDatastoreQuery("TwinLunch").Ancestor(twinLunchListKey), &stored); err != nil {
+		t.Fatal(err)
+	}
+	var ids = make(map[string]int64)
+	for _, twinLunch := range stored {
+		ids[twinLunch.User1] = twinLunch.Key.ID
+	}
+	if len(stored) != 2 || ids["U1"] != current.Key.ID || ids["U3"] == 1000 || ids["U3"] == 0 {
+		t.Errorf("stored IDs = %v, want %d kept for U1 and an allocated ID for U3", ids, current.Key.ID)
+	}
+}
+
+func TestImportRejectsLargeGroups(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	var response = postImport(t, `[{"User1":"U1","User2":"U2","Others":["U3"]}]`)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "entry 0: 3 users") {
+		t.Errorf("import status = %d %q, want the group rejected", response.Code, response.Body)
+	}
+	if stored := storedTwinLunches(t, fd); len(stored) != 0 {
+		t.Errorf("stored twin lunches = %q, want none", stored)
+	}
+}
@@ -0,0 +1,851 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+	"google.golang.org/api/iterator"
+)
+
+// setupFakes replaces the slack and datastore clients with fakes and resets the bot state, it is restored when t ends.
+func setupFakes(t *testing.T) (*fakeSlack, *fakeDatastore) {
+	var fs, fd = newFakeSlack(), newFakeDatastore()
+
+	var (
+		savedSlackClient     = slackClient
+		savedDatastoreClient = datastoreClient
+		savedDeliveryDelay   = deliveryDelay
+		savedTwinLunches     = twinLunches
+		savedAdmins          = twinLunchAdmins
+		savedHomeUsers       = homeUsers
+		savedUnpairedReplies = unpairedReplies
+		savedUnsaved         = unsavedTwinLunches
+		savedRound           = currentRound
+	)
+
+	slackClient, datastoreClient = fs, fd
+	deliveryDelay = 0
+	twinLunches = newTwinLunchStore()
+	twinLunchAdmins = make(map[string]struct{})
+	homeUsers = make(map[string]struct{})
+	unpairedReplies = make(map[string]time.Time)
+	unsavedTwinLunches = make(map[*TwinLunch]struct{})
+	currentRound = 1
+
+	twinLunchUsersMu.Lock()
+	twinLunchUsers = make(map[string]*TwinLunchUser)
+	twinLunchUsersMu.Unlock()
+
+	t.Cleanup(func() {
+		deliveries.Wait()
+
+		slackClient, datastoreClient = savedSlackClient, savedDatastoreClient
+		deliveryDelay = savedDeliveryDelay
+		twinLunches = savedTwinLunches
+		twinLunchAdmins = savedAdmins
+		homeUsers = savedHomeUsers
+		unpairedReplies = savedUnpairedReplies
+		unsavedTwinLunches = savedUnsaved
+		currentRound = savedRound
+	})
+
+	return fs, fd
+}
+
+// logOutput holds the lines logged during a test, it is guarded by logMu like the writes of the loggers.
+type logOutput struct {
+	buf bytes.Buffer
+}
+
+func (o *logOutput) String() string {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	return o.buf.String()
+}
+
+// captureLogs replaces the main logger until t ends.
+func captureLogs(t *testing.T) *logOutput {
+	var output = &logOutput{}
+	var savedLogger = logger
+	logger = newFieldLogger(&output.buf, "main: ")
+	t.Cleanup(func() { logger = savedLogger })
+	return output
+}
+
+// dmChannel is the direct message channel the fake slack opens with user.
+func dmChannel(user string) string {
+	return "D" + user
+}
+
+// fakeCalls counts the calls of each method of a fake, and holds the errors to return from the next ones.
+type fakeCalls struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	failures map[string][]error
+}
+
+// callCount returns how many times method was called.
+func (c *fakeCalls) callCount(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls[method]
+}
+
+// failNext makes the next calls of method return errs, one error per call.
+func (c *fakeCalls) failNext(method string, errs ...error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failures == nil {
+		c.failures = make(map[string][]error)
+	}
+	c.failures[method] = append(c.failures[method], errs...)
+}
+
+func (c *fakeCalls) failure(method string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[method]++
+
+	var errs = c.failures[method]
+	if len(errs) == 0 {
+		return nil
+	}
+	c.failures[method] = errs[1:]
+	return errs[0]
+}
+
+type postedMessage struct {
+	Channel, TS, Text string
+}
+
+// fakeSlack is a slackAPI recording the messages, pins and views, every user exists and is active unless told otherwise.
+type fakeSlack struct {
+	fakeCalls
+
+	mu       sync.Mutex
+	nextTS   int
+	inactive map[string]bool
+	// unknown users fail the whole users lookup, like the slack API does
+	unknown map[string]bool
+	posted  []postedMessage
+	updated []postedMessage
+	deleted []postedMessage
+	pins    []slack.ItemRef
+	views   map[string]slack.HomeTabViewRequest
+	topics  []string
+}
+
+func newFakeSlack() *fakeSlack {
+	return &fakeSlack{inactive: make(map[string]bool), unknown: make(map[string]bool), views: make(map[string]slack.HomeTabViewRequest)}
+}
+
+// messagesTo returns the text of the messages posted to channel, in order.
+func (s *fakeSlack) messagesTo(channel string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var texts []string
+	for _, posted := range s.posted {
+		if posted.Channel == channel {
+			texts = append(texts, posted.Text)
+		}
+	}
+	return texts
+}
+
+func msgText(channel string, options []slack.MsgOption) string {
+	var _, values, _ = slack.UnsafeApplyMsgOptions("", channel, "", options...)
+	return values.Get("text")
+}
+
+func (s *fakeSlack) AuthTest() (*slack.AuthTestResponse, error) {
+	if err := s.failure("AuthTest"); err != nil {
+		return nil, err
+	}
+	return &slack.AuthTestResponse{TeamID: "T1", BotID: "B1", UserID: "UBOT"}, nil
+}
+
+func (s *fakeSlack) GetUsersInfo(users ...string) (*[]slack.User, error) {
+	if err := s.failure("GetUsersInfo"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var infos = make([]slack.User, 0, len(users))
+	for _, user := range users {
+		if s.unknown[user] {
+			return nil, slack.SlackErrorResponse{Err: "user_not_found"}
+		}
+		infos = append(infos, slack.User{ID: user, Deleted: s.inactive[user]})
+	}
+	return &infos, nil
+}
+
+func (s *fakeSlack) OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	if err := s.failure("OpenConversation"); err != nil {
+		return nil, false, false, err
+	}
+
+	var channel slack.Channel
+	channel.ID = dmChannel(strings.Join(params.Users, ","))
+	return &channel, false, false, nil
+}
+
+func (s *fakeSlack) SetTopicOfConversation(channel string, topic string) (*slack.Channel, error) {
+	if err := s.failure("SetTopicOfConversation"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.topics = append(s.topics, topic)
+
+	var result slack.Channel
+	result.ID = channel
+	return &result, nil
+}
+
+func (s *fakeSlack) PostMessage(channel string, options ...slack.MsgOption) (string, string, error) {
+	if err := s.failure("PostMessage"); err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextTS++
+	var ts = fmt.Sprintf("%d.000000", s.nextTS)
+	s.posted = append(s.posted, postedMessage{channel, ts, msgText(channel, options)})
+	return channel, ts, nil
+}
+
+func (s *fakeSlack) UpdateMessage(channel string, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	if err := s.failure("UpdateMessage"); err != nil {
+		return "", "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var text = msgText(channel, options)
+	s.updated = append(s.updated, postedMessage{channel, timestamp, text})
+	return channel, timestamp, text, nil
+}
+
+func (s *fakeSlack) DeleteMessage(channel string, timestamp string) (string, string, error) {
+	if err := s.failure("DeleteMessage"); err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleted = append(s.deleted, postedMessage{Channel: channel, TS: timestamp})
+	return channel, timestamp, nil
+}
+
+func (s *fakeSlack) AddPin(channel string, item slack.ItemRef) error {
+	if err := s.failure("AddPin"); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pins = append(s.pins, item)
+	return nil
+}
+
+func (s *fakeSlack) RemovePin(channel string, item slack.ItemRef) error {
+	if err := s.failure("RemovePin"); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, pin := range s.pins {
+		if pin == item {
+			s.pins = append(s.pins[:i], s.pins[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *fakeSlack) ListPins(channel string) ([]slack.Item, *slack.Paging, error) {
+	if err := s.failure("ListPins"); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []slack.Item
+	for _, pin := range s.pins {
+		if pin.Channel == channel {
+			items = append(items, slack.NewMessageItem(channel, &slack.Message{Msg: slack.Msg{Timestamp: pin.Timestamp, BotID: slackBotID}}))
+		}
+	}
+	return items, &slack.Paging{}, nil
+}
+
+func (s *fakeSlack) AddReaction(name string, item slack.ItemRef) error {
+	return s.failure("AddReaction")
+}
+
+func (s *fakeSlack) RemoveReaction(name string, item slack.ItemRef) error {
+	return s.failure("RemoveReaction")
+}
+
+func (s *fakeSlack) GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error) {
+	return nil, s.failure("GetReactions")
+}
+
+func (s *fakeSlack) PublishView(userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error) {
+	if err := s.failure("PublishView"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.views[userID] = view
+	return &slack.ViewResponse{}, nil
+}
+
+func (s *fakeSlack) GetFile(downloadURL string, writer io.Writer) error {
+	if err := s.failure("GetFile"); err != nil {
+		return err
+	}
+	var _, err = io.WriteString(writer, downloadURL)
+	return err
+}
+
+func (s *fakeSlack) UploadFile(params slack.FileUploadParameters) (*slack.File, error) {
+	if err := s.failure("UploadFile"); err != nil {
+		return nil, err
+	}
+
+	var content bytes.Buffer
+	if params.Reader != nil {
+		if _, err := io.Copy(&content, params.Reader); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, channel := range params.Channels {
+		s.posted = append(s.posted, postedMessage{Channel: channel, Text: params.Filename + "\n" + content.String()})
+	}
+	return &slack.File{Name: params.Filename}, nil
+}
+
+// fakeAcknowledger is an eventAcknowledger recording the acknowledgements.
+type fakeAcknowledger struct {
+	acks chan socketmode.Response
+}
+
+func newFakeAcknowledger() *fakeAcknowledger {
+	return &fakeAcknowledger{make(chan socketmode.Response, 10)}
+}
+
+func (a *fakeAcknowledger) Ack(req socketmode.Request, payload ...interface{}) {
+	var response = socketmode.Response{EnvelopeID: req.EnvelopeID}
+	if len(payload) != 0 {
+		response.Payload = payload[0]
+	}
+	a.acks <- response
+}
+
+type fakeEntity struct {
+	key        *datastore.Key
+	properties []datastore.Property
+}
+
+// fakeDatastore is an in memory datastoreAPI, the transactions are serialized and aren't rolled back.
+type fakeDatastore struct {
+	fakeCalls
+
+	txMu     sync.Mutex
+	mu       sync.Mutex
+	nextID   int64
+	entities map[string]fakeEntity
+	delays   map[string][]time.Duration
+}
+
+// delayNext makes the next calls of method take delays, one delay per call, unless their context is done before.
+func (d *fakeDatastore) delayNext(method string, delays ...time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.delays == nil {
+		d.delays = make(map[string][]time.Duration)
+	}
+	d.delays[method] = append(d.delays[method], delays...)
+}
+
+// call waits for the delay of the call, then returns its error.
+func (d *fakeDatastore) call(ctx context.Context, method string) error {
+	d.mu.Lock()
+	var delay time.Duration
+	if delays := d.delays[method]; len(delays) != 0 {
+		delay, d.delays[method] = delays[0], delays[1:]
+	}
+	d.mu.Unlock()
+
+	if delay != 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			d.failure(method)
+			return ctx.Err()
+		}
+	}
+
+	return d.failure(method)
+}
+
+func newFakeDatastore() *fakeDatastore {
+	return &fakeDatastore{entities: make(map[string]fakeEntity)}
+}
+
+func (d *fakeDatastore) AllocateIDs(ctx context.Context, keys []*datastore.Key) ([]*datastore.Key, error) {
+	if err := d.call(ctx, "AllocateIDs"); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var allocated = make([]*datastore.Key, len(keys))
+	for i, key := range keys {
+		d.nextID++
+		allocated[i] = datastore.IDKey(key.Kind, d.nextID, key.Parent)
+	}
+	return allocated, nil
+}
+
+func (d *fakeDatastore) Get(ctx context.Context, key *datastore.Key, dst interface{}) error {
+	if err := d.call(ctx, "Get"); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	var entity, ok = d.entities[key.Encode()]
+	d.mu.Unlock()
+
+	if !ok {
+		return datastore.ErrNoSuchEntity
+	}
+	return loadFakeEntity(dst, entity)
+}
+
+func (d *fakeDatastore) GetAll(ctx context.Context, q *datastoreQuery, dst interface{}) ([]*datastore.Key, error) {
+	if err := d.call(ctx, "GetAll"); err != nil {
+		return nil, err
+	}
+
+	var entities = d.query(q)
+
+	var keys = make([]*datastore.Key, 0, len(entities))
+	for _, entity := range entities {
+		keys = append(keys, entity.key)
+	}
+
+	if dst == nil {
+		return keys, nil
+	}
+
+	var slice = reflect.ValueOf(dst).Elem()
+	var elemType = slice.Type().Elem()
+	for _, entity := range entities {
+		var elem reflect.Value
+		if elemType.Kind() == reflect.Ptr {
+			elem = reflect.New(elemType.Elem())
+		} else {
+			elem = reflect.New(elemType)
+		}
+		if err := loadFakeEntity(elem.Interface(), entity); err != nil {
+			return nil, err
+		}
+		if elemType.Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+
+	return keys, nil
+}
+
+func (d *fakeDatastore) Run(ctx context.Context, q *datastoreQuery) datastoreIterator {
+	if err := d.call(ctx, "Run"); err != nil {
+		return &fakeIterator{err: err}
+	}
+	return &fakeIterator{entities: d.query(q)}
+}
+
+func (d *fakeDatastore) Put(ctx context.Context, key *datastore.Key, src interface{}) (*datastore.Key, error) {
+	if err := d.call(ctx, "Put"); err != nil {
+		return nil, err
+	}
+	return d.put(key, src)
+}
+
+func (d *fakeDatastore) PutMulti(ctx context.Context, keys []*datastore.Key, src interface{}) ([]*datastore.Key, error) {
+	if err := d.call(ctx, "PutMulti"); err != nil {
+		return nil, err
+	}
+	return d.putMulti(keys, src)
+}
+
+func (d *fakeDatastore) Delete(ctx context.Context, key *datastore.Key) error {
+	if err := d.call(ctx, "Delete"); err != nil {
+		return err
+	}
+	d.delete(key)
+	return nil
+}
+
+func (d *fakeDatastore) DeleteMulti(ctx context.Context, keys []*datastore.Key) error {
+	if err := d.call(ctx, "DeleteMulti"); err != nil {
+		return err
+	}
+	d.delete(keys...)
+	return nil
+}
+
+func (d *fakeDatastore) RunInTransaction(ctx context.Context, f func(tx datastoreTransaction) error) error {
+	if err := d.call(ctx, "RunInTransaction"); err != nil {
+		return err
+	}
+
+	d.txMu.Lock()
+	defer d.txMu.Unlock()
+
+	return f(fakeTransaction{d})
+}
+
+func (d *fakeDatastore) Close() error {
+	return nil
+}
+
+func (d *fakeDatastore) put(key *datastore.Key, src interface{}) (*datastore.Key, error) {
+	var properties, err = saveFakeEntity(src)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if key.Incomplete() {
+		d.nextID++
+		key = datastore.IDKey(key.Kind, d.nextID, key.Parent)
+	}
+	d.entities[key.Encode()] = fakeEntity{key, properties}
+	return key, nil
+}
+
+func (d *fakeDatastore) putMulti(keys []*datastore.Key, src interface{}) ([]*datastore.Key, error) {
+	var slice = reflect.ValueOf(src)
+	if slice.Len() != len(keys) {
+		return nil, errors.New("fake datastore: keys and src have different lengths")
+	}
+
+	var put = make([]*datastore.Key, len(keys))
+	for i, key := range keys {
+		var elem = slice.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		var err error
+		if put[i], err = d.put(key, elem.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return put, nil
+}
+
+func (d *fakeDatastore) delete(keys ...*datastore.Key) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, key := range keys {
+		delete(d.entities, key.Encode())
+	}
+}
+
+// query supports the kind, ancestor, filters, order, keys only and limit of a query, which is all the bot uses.
+func (d *fakeDatastore) query(q *datastoreQuery) []fakeEntity {
+	d.mu.Lock()
+	var entities []fakeEntity
+	for _, entity := range d.entities {
+		if entity.key.Kind == q.kind && hasAncestor(entity.key, q.ancestor) {
+			entities = append(entities, entity)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, filter := range q.filters {
+		var kept = entities[:0]
+		for _, entity := range entities {
+			if matchesFilter(entity, filter) {
+				kept = append(kept, entity)
+			}
+		}
+		entities = kept
+	}
+
+	// the map order is random, the key order stands for the insertion order of the real datastore
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].key.String() < entities[j].key.String()
+	})
+
+	for i := len(q.orders) - 1; i >= 0; i-- {
+		var name = strings.TrimPrefix(q.orders[i], "-")
+		var descending = name != q.orders[i]
+		sort.SliceStable(entities, func(i, j int) bool {
+			var a, _ = propertyValue(entities[i], name)
+			var b, _ = propertyValue(entities[j], name)
+			var cmp, _ = compareFakeValues(a, b)
+			if descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+
+	if q.limit > 0 && q.limit < len(entities) {
+		entities = entities[:q.limit]
+	}
+
+	if q.keysOnly {
+		for i := range entities {
+			entities[i].properties = nil
+		}
+	}
+
+	return entities
+}
+
+func hasAncestor(key *datastore.Key, ancestor *datastore.Key) bool {
+	if ancestor == nil {
+		return true
+	}
+	for ; key != nil; key = key.Parent {
+		if key.Equal(ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+func propertyValue(entity fakeEntity, name string) (interface{}, bool) {
+	for _, property := range entity.properties {
+		if property.Name == name {
+			return property.Value, true
+		}
+	}
+	return nil, false
+}
+
+// matchesFilter compares a property with a filter value, a slice property matches if one of its values does.
+func matchesFilter(entity fakeEntity, filter datastoreFilter) bool {
+	var property, ok = propertyValue(entity, filter.field)
+	if !ok {
+		return false
+	}
+
+	var values = []interface{}{property}
+	if multiple, ok := property.([]interface{}); ok {
+		values = multiple
+	}
+
+	for _, v := range values {
+		var cmp, ok = compareFakeValues(v, filter.value)
+		if !ok {
+			continue
+		}
+		switch filter.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "=":
+			ok = cmp == 0
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func compareFakeValues(a interface{}, b interface{}) (int, bool) {
+	var normalize = func(v interface{}) interface{} {
+		var rv = reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int()
+		}
+		return v
+	}
+	a, b = normalize(a), normalize(b)
+
+	switch a := a.(type) {
+	case int64:
+		var b, ok = b.(int64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		var b, ok = b.(string)
+		return strings.Compare(a, b), ok
+	case bool:
+		var b, ok = b.(bool)
+		if !ok || a == b {
+			return 0, ok
+		}
+		if !a {
+			return -1, true
+		}
+		return 1, true
+	case time.Time:
+		var b, ok = b.(time.Time)
+		switch {
+		case !ok:
+			return 0, false
+		case a.Before(b):
+			return -1, true
+		case a.After(b):
+			return 1, true
+		}
+		return 0, true
+	case *datastore.Key:
+		var b, ok = b.(*datastore.Key)
+		if !ok || a.Equal(b) {
+			return 0, ok
+		}
+		return strings.Compare(a.String(), b.String()), true
+	}
+	return 0, false
+}
+
+func saveFakeEntity(src interface{}) ([]datastore.Property, error) {
+	if pls, ok := src.(datastore.PropertyLoadSaver); ok {
+		return pls.Save()
+	}
+	return datastore.SaveStruct(src)
+}
+
+// loadFakeEntity loads the properties of entity in dst, and its key in the __key__ field of dst if there is one.
+func loadFakeEntity(dst interface{}, entity fakeEntity) error {
+	if dst == nil {
+		return nil
+	}
+
+	var err error
+	if pls, ok := dst.(datastore.PropertyLoadSaver); ok {
+		err = pls.Load(entity.properties)
+	} else {
+		err = datastore.LoadStruct(dst, entity.properties)
+	}
+	if err != nil {
+		return err
+	}
+
+	var value = reflect.ValueOf(dst).Elem()
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < value.NumField(); i++ {
+		if strings.HasPrefix(value.Type().Field(i).Tag.Get("datastore"), "__key__") {
+			value.Field(i).Set(reflect.ValueOf(entity.key))
+		}
+	}
+	return nil
+}
+
+type fakeIterator struct {
+	entities []fakeEntity
+	err      error
+}
+
+func (it *fakeIterator) Next(dst interface{}) (*datastore.Key, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if len(it.entities) == 0 {
+		return nil, iterator.Done
+	}
+
+	var entity = it.entities[0]
+	it.entities = it.entities[1:]
+	return entity.key, loadFakeEntity(dst, entity)
+}
+
+type fakeTransaction struct {
+	d *fakeDatastore
+}
+
+func (tx fakeTransaction) Get(key *datastore.Key, dst interface{}) error {
+	return tx.d.Get(context.Background(), key, dst)
+}
+
+func (tx fakeTransaction) Run(ctx context.Context, q *datastoreQuery) datastoreIterator {
+	return tx.d.Run(ctx, q)
+}
+
+func (tx fakeTransaction) Put(key *datastore.Key, src interface{}) (*datastore.PendingKey, error) {
+	var _, err = tx.d.Put(context.Background(), key, src)
+	return nil, err
+}
+
+func (tx fakeTransaction) PutMulti(keys []*datastore.Key, src interface{}) ([]*datastore.PendingKey, error) {
+	var _, err = tx.d.PutMulti(context.Background(), keys, src)
+	return nil, err
+}
+
+func (tx fakeTransaction) Delete(key *datastore.Key) error {
+	return tx.d.Delete(context.Background(), key)
+}
+
+func (tx fakeTransaction) DeleteMulti(keys []*datastore.Key) error {
+	return tx.d.DeleteMulti(context.Background(), keys)
+}
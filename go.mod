@@ -9,4 +9,5 @@ require (
 	github.com/slack-go/slack v0.10.2
 	google.golang.org/api v0.70.0
 	google.golang.org/genproto v0.0.0-20220222213610-43724f9ea8cf
+	google.golang.org/grpc v1.44.0
 )
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"google.golang.org/api/iterator"
+)
+
+var (
+	// graphMinCohort is the minimum number of distinct users for a graph to be exported
+	graphMinCohort = 5
+	// graphTimeout bounds the export of the graph, from the history scan to the end of the upload
+	graphTimeout = 5 * time.Minute
+)
+
+type graphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+type pairingGraph struct {
+	Nodes []string    `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// newGraphHasher returns a function hashing user IDs with a random salt, so that nodes can't be matched across exports.
+func newGraphHasher() (func(user string) string, error) {
+	var salt = make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error generating graph salt: %w", err)
+	}
+
+	return func(user string) string {
+		var sum = sha256.Sum256(append(append([]byte(nil), salt...), user...))
+		return "n" + hex.EncodeToString(sum[:6])
+	}, nil
+}
+
+// getPairingGraph streams the whole history, only the hashed nodes and the edges are kept in memory.
+// The scan isn't retried, ctx bounds it as a whole.
+func getPairingGraph(ctx context.Context) (*pairingGraph, error) {
+	var hash, err = newGraphHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes = make(map[string]struct{})
+	var weights = make(map[[2]string]int)
+
+	var it = datastoreClient.Run(ctx, newDatastoreQuery("TwinLunchHistory"))
+
+	for {
+		var history TwinLunchHistory
+		var _, err = it.Next(&history)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error listing history in datastore: %w", err)
+		}
+
+		// groups add an edge between each two members
+		for i, user1 := range history.Users {
+			for _, user2 := range history.Users[i+1:] {
+				var edge = [2]string{hash(user1), hash(user2)}
+				if edge[1] < edge[0] {
+					edge[0], edge[1] = edge[1], edge[0]
+				}
+
+				nodes[edge[0]], nodes[edge[1]] = struct{}{}, struct{}{}
+				weights[edge]++
+			}
+		}
+	}
+
+	var graph = &pairingGraph{
+		Nodes: make([]string, 0, len(nodes)),
+		Edges: make([]graphEdge, 0, len(weights)),
+	}
+	for node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for edge, weight := range weights {
+		graph.Edges = append(graph.Edges, graphEdge{edge[0], edge[1], weight})
+	}
+
+	sort.Strings(graph.Nodes)
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Source != graph.Edges[j].Source {
+			return graph.Edges[i].Source < graph.Edges[j].Source
+		}
+		return graph.Edges[i].Target < graph.Edges[j].Target
+	})
+
+	return graph, nil
+}
+
+func writeGraphDOT(buf *bytes.Buffer, graph *pairingGraph) {
+	buf.WriteString("graph twinlunch {\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(buf, "  %s;\n", node)
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(buf, "  %s -- %s [weight=%d];\n", edge.Source, edge.Target, edge.Weight)
+	}
+	buf.WriteString("}\n")
+}
+
+func handleGraphCommand(command slack.SlashCommand) {
+	var format = strings.ToLower(strings.TrimSpace(command.Text))
+	if format == "" {
+		format = "dot"
+	}
+
+	if format != "dot" && format != "json" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "graphUsage", nil))
+		return
+	}
+
+	// the history scan may take a while, it mustn't hold the run loop
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+		exportGraph(command, format)
+	}()
+}
+
+// exportGraph uploads the pairing graph in format, reporting through a placeholder message.
+func exportGraph(command slack.SlashCommand, format string) {
+	var channel, ts = sendPlaceholderToUser(command.UserID)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), graphTimeout)
+	defer cancel()
+
+	var graph, err = getPairingGraph(ctx)
+	if err != nil {
+		commandLogger(command).Println(err)
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "graphError", nil))
+		return
+	}
+
+	if len(graph.Nodes) < graphMinCohort {
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "graphTooSmall", messageData{"Min": graphMinCohort}))
+		return
+	}
+
+	var buf bytes.Buffer
+	if format == "json" {
+		if err := json.NewEncoder(&buf).Encode(graph); err != nil {
+			commandLogger(command).Printf("error encoding graph: %s", err)
+			replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "graphError", nil))
+			return
+		}
+	} else {
+		writeGraphDOT(&buf, graph)
+	}
+
+	if err := uploadFileToUser(
+		command.UserID,
+		"twinlunch-graph."+format,
+		message("graphFileTitle", nil),
+		&buf,
+	); err != nil {
+		commandLogger(command).Println(err)
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "graphError", nil))
+		return
+	}
+
+	replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "graphDone", messageData{"Nodes": len(graph.Nodes), "Edges": len(graph.Edges)}))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+func TestHandleGraphCommand(t *testing.T) {
+	var fs, fd = setupFakes(t)
+
+	// the scan is bound by graphTimeout, not by the timeout of a datastore call
+	var savedTimeout = datastoreTimeout
+	datastoreTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { datastoreTimeout = savedTimeout })
+	fd.delayNext("Run", 50*time.Millisecond)
+
+	for i, users := range [][]string{{"U1", "U2"}, {"U3", "U4", "U5"}, {"U1", "U2"}} {
+		if _, err := fd.Put(context.Background(), datastore.IDKey("TwinLunchHistory", int64(i+1), nil), &TwinLunchHistory{Users: users, Round: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var start = time.Now()
+	handleGraphCommand(slack.SlashCommand{Command: "/twinlunch-graph", UserID: "UADMIN", Text: "json"})
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("command took %s, want the export to run in the background", elapsed)
+	}
+	deliveries.Wait()
+
+	var messages = fs.messagesTo(dmChannel("UADMIN"))
+	if len(messages) != 2 || !strings.HasPrefix(messages[1], "twinlunch-graph.json\n") {
+		t.Fatalf("messages = %q, want the placeholder and the file", messages)
+	}
+
+	if len(fs.updated) != 1 || !strings.Contains(fs.updated[0].Text, message("graphDone", messageData{"Nodes": 5, "Edges": 4})) {
+		t.Errorf("placeholder updates = %q, want the graph done message", fs.updated)
+	}
+}
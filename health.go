@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var (
+	// healthMu guards the readiness state, which is written by start and receiveEvents
+	healthMu       sync.Mutex
+	startCompleted bool
+	slackConnected bool
+	scopeWarnings  []string
+)
+
+func registerHealthChecks() {
+	http.HandleFunc("/healthz", handleLivenessRequest)
+	http.HandleFunc("/readyz", handleReadinessRequest)
+}
+
+// handleLivenessRequest only tells the HTTP server is up, it doesn't wait for warmup.
+// Like the readiness check it accepts HEAD requests, which some probes send.
+func handleLivenessRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadinessRequest tells the bot is started and connected to slack, the missing scopes are listed as warnings.
+func handleReadinessRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	healthMu.Lock()
+	var started, connected, warnings = startCompleted, slackConnected, scopeWarnings
+	healthMu.Unlock()
+
+	switch {
+	case !started:
+		http.Error(w, "not started", http.StatusServiceUnavailable)
+		return
+	case !connected:
+		http.Error(w, "not connected to slack", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(w, "ready")
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+}
+
+func setStartCompleted() {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	startCompleted = true
+}
+
+func setSlackConnected(connected bool) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	slackConnected = connected
+}
+
+func setScopeWarnings(warnings []string) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	scopeWarnings = warnings
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthChecks(t *testing.T) {
+	setStartCompleted()
+	setSlackConnected(true)
+	t.Cleanup(func() {
+		healthMu.Lock()
+		startCompleted, slackConnected = false, false
+		healthMu.Unlock()
+	})
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/healthz", handleLivenessRequest)
+	mux.HandleFunc("/readyz", handleReadinessRequest)
+	var server = httptest.NewServer(mux)
+	defer server.Close()
+
+	var tests = []struct {
+		method, path string
+		wantStatus   int
+		wantBody     string
+	}{
+		{http.MethodGet, "/healthz", http.StatusOK, "ok\n"},
+		{http.MethodHead, "/healthz", http.StatusOK, ""},
+		{http.MethodPost, "/healthz", http.StatusMethodNotAllowed, "method not allowed\n"},
+		{http.MethodGet, "/readyz", http.StatusOK, "ready\n"},
+		{http.MethodHead, "/readyz", http.StatusOK, ""},
+		{http.MethodPost, "/readyz", http.StatusMethodNotAllowed, "method not allowed\n"},
+	}
+
+	for _, test := range tests {
+		var request, err = http.NewRequest(test.method, server.URL+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.StatusCode != test.wantStatus || string(body) != test.wantBody {
+			t.Errorf("%s %s: status %d and body %q, want %d and %q", test.method, test.path, response.StatusCode, body, test.wantStatus, test.wantBody)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+var (
+	maxTwinLunchesPerUser int
+	// the program window is [programStart, programEnd), zero values leave it open
+	programStart, programEnd time.Time
+	// repeatAvoidRounds is the number of rounds during which automatic pairing avoids pairing the same users again,
+	// zero disables it
+	repeatAvoidRounds int
+)
+
+// TwinLunchHistory records a pairing, it is kept after the pairing is removed.
+// It has the same ID as the pairing, Messages is updated when the pairing ends.
+type TwinLunchHistory struct {
+	Users     []string
+	CreatedAt time.Time
+	Round     int
+	Messages  int `datastore:",noindex"`
+}
+
+func historyKey(twinLunch *TwinLunch) *datastore.Key {
+	return datastore.IDKey("TwinLunchHistory", twinLunch.Key.ID, nil)
+}
+
+func recordHistory(twinLunch *TwinLunch) {
+	var history = &TwinLunchHistory{twinLunch.Members(), twinLunch.CreatedAt, twinLunch.Round, 0}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, historyKey(twinLunch), history)
+		return err
+	}); err != nil {
+		logger.Printf("error writing history in datastore: %s", err)
+	}
+}
+
+// recordHistoryMessages saves the message count of an ended pairing in its history.
+func recordHistoryMessages(twinLunch *TwinLunch) {
+	if twinLunch.Key == nil {
+		return
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return runInTransaction(ctx, func(tx datastoreTransaction) error {
+			var history TwinLunchHistory
+			if err := tx.Get(historyKey(twinLunch), &history); err != nil {
+				return err
+			}
+			history.Messages = twinLunch.Messages
+			var _, err = tx.Put(historyKey(twinLunch), &history)
+			return err
+		})
+	}); err != nil && !errors.Is(err, datastore.ErrNoSuchEntity) {
+		logger.Printf("error writing history in datastore: %s", err)
+	}
+}
+
+func inProgramWindow(t time.Time) bool {
+	return !t.Before(programStart) && (programEnd.IsZero() || t.Before(programEnd))
+}
+
+func countProgramTwinLunches(user string) (int, error) {
+	var history, err = getProgramHistory(user)
+	return len(history), err
+}
+
+// getUserHistory returns all the pairings of user, oldest first.
+func getUserHistory(user string) ([]*TwinLunchHistory, error) {
+	var history []*TwinLunchHistory
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		history = nil
+		var _, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchHistory").Filter("Users =", user), &history)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("error reading history from datastore: %w", err)
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].CreatedAt.Before(history[j].CreatedAt)
+	})
+
+	return history, nil
+}
+
+func getProgramHistory(user string) ([]*TwinLunchHistory, error) {
+	var history, err = getUserHistory(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var inWindow = make([]*TwinLunchHistory, 0, len(history))
+	for _, entry := range history {
+		if inProgramWindow(entry.CreatedAt) {
+			inWindow = append(inWindow, entry)
+		}
+	}
+
+	return inWindow, nil
+}
+
+// getRecentPairs returns the pairs to avoid when pairing round, keyed like the excluded pairs.
+// They are the pairs of the repeatAvoidRounds rounds before round, from round-repeatAvoidRounds to round-1,
+// and the pairs already created in round itself.
+func getRecentPairs(round int) (map[string]struct{}, error) {
+	var recent = make(map[string]struct{})
+	if repeatAvoidRounds <= 0 {
+		return recent, nil
+	}
+
+	var history []*TwinLunchHistory
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		history = nil
+		var _, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchHistory").Filter("Round >=", round-repeatAvoidRounds), &history)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("error reading history from datastore: %w", err)
+	}
+
+	for _, entry := range history {
+		for i, user1 := range entry.Users {
+			for _, user2 := range entry.Users[i+1:] {
+				recent[exclusionKey(user1, user2).Name] = struct{}{}
+			}
+		}
+	}
+
+	return recent, nil
+}
+
+func isCapped(count int) bool {
+	return maxTwinLunchesPerUser > 0 && count >= maxTwinLunchesPerUser
+}
+
+func handleMyHistoryCommand(command slack.SlashCommand) {
+	var history, err = getProgramHistory(command.UserID)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	var count = len(history)
+
+	var start, end string
+	if !programStart.IsZero() {
+		start = programStart.Format(configDateLayout)
+	}
+	if !programEnd.IsZero() {
+		end = programEnd.AddDate(0, 0, -1).Format(configDateLayout)
+	}
+
+	var rounds []string
+	for _, entry := range history {
+		if entry.Round > 0 {
+			rounds = append(rounds, strconv.Itoa(entry.Round))
+		}
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "myHistory", messageData{
+		"Count":  count,
+		"Start":  start,
+		"End":    end,
+		"Rounds": strings.Join(rounds, ", "),
+		"Max":    maxTwinLunchesPerUser,
+		"Capped": isCapped(count),
+	}))
+}
+
+type historyEntryData struct {
+	Partner string
+	Others  []string
+	Round   int
+	Date    string
+}
+
+func handleHistoryCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "historyUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+
+	var history, err = getUserHistory(user)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	var entries = make([]historyEntryData, 0, len(history))
+	for _, entry := range history {
+		var partners []string
+		for _, u := range entry.Users {
+			if u != user {
+				partners = append(partners, u)
+			}
+		}
+		if len(partners) == 0 {
+			continue
+		}
+		entries = append(entries, historyEntryData{partners[0], partners[1:], entry.Round, entry.CreatedAt.Format(configDateLayout)})
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "history", messageData{"User": user, "Entries": entries}))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+var (
+	// homeActions maps the App Home buttons to the commands they run, with the same permissions
+	homeActions = map[string]string{
+		"home_join":  "/twinlunch-join",
+		"home_leave": "/twinlunch-leave",
+		"home_quit":  "/twinlunch-quit",
+		"home_list":  "/twinlunch-list",
+		"home_clear": "/twinlunch-clear",
+	}
+
+	// homeUsers are the users who opened the App Home since startup, only their view is kept up to date
+	homeUsers = make(map[string]struct{})
+)
+
+func handleHomeOpened(user string) {
+	homeUsers[user] = struct{}{}
+	updateHome(user)
+}
+
+// updateHome publishes the App Home view of the users who opened it, after their pairing or admin state changed.
+// It never shows who the partners are, not even to admins.
+func updateHome(users ...string) {
+	for _, user := range users {
+		if _, ok := homeUsers[user]; !ok {
+			continue
+		}
+
+		var view, err = homeView(user)
+		if err != nil {
+			logger.Println(err)
+			continue
+		}
+
+		var user = user
+		enqueueDelivery("home/"+user, delivery{send: func() error {
+			if _, err := slackClient.PublishView(user, view, ""); err != nil {
+				return fmt.Errorf("error publishing home view: %w", err)
+			}
+			return nil
+		}})
+	}
+}
+
+func homeView(user string) (slack.HomeTabViewRequest, error) {
+	var candidate, err = isPoolCandidate(user)
+	if err != nil {
+		return slack.HomeTabViewRequest{}, err
+	}
+
+	var blocks = []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, messageTo(user, "homeTitle", nil), true, false)),
+	}
+
+	var buttons []slack.BlockElement
+
+	if twinLunch, ok := twinLunches.Get(user); ok {
+		blocks = append(blocks, homeSection(messageTo(user, "homePaired", messageData{"Round": twinLunch.Round, "Partners": len(twinLunch.Partners(user))})))
+		buttons = append(buttons, homeButton(user, "home_quit", "homeQuitButton", "homeQuitConfirm"))
+	} else {
+		blocks = append(blocks, homeSection(messageTo(user, "homeUnpaired", nil)))
+	}
+
+	if candidate {
+		blocks = append(blocks, homeSection(messageTo(user, "homeInPool", nil)))
+		buttons = append(buttons, homeButton(user, "home_leave", "homeLeaveButton", ""))
+	} else {
+		buttons = append(buttons, homeButton(user, "home_join", "homeJoinButton", ""))
+	}
+
+	blocks = append(blocks, slack.NewActionBlock("home_actions", buttons...))
+
+	if _, ok := twinLunchAdmins[user]; ok {
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			homeSection(messageTo(user, "homeAdmin", nil)),
+			slack.NewActionBlock("home_admin_actions",
+				homeButton(user, "home_list", "homeListButton", ""),
+				homeButton(user, "home_clear", "homeClearButton", "homeClearConfirm"),
+			),
+		)
+	}
+
+	return slack.HomeTabViewRequest{Type: slack.VTHomeTab, Blocks: slack.Blocks{BlockSet: blocks}}, nil
+}
+
+func homeSection(text string) *slack.SectionBlock {
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+}
+
+// homeButton creates an App Home button, confirm is the message ID of its confirmation dialog or empty.
+func homeButton(user string, actionID string, text string, confirm string) *slack.ButtonBlockElement {
+	var button = slack.NewButtonBlockElement(actionID, "", slack.NewTextBlockObject(slack.PlainTextType, messageTo(user, text, nil), true, false))
+
+	if confirm != "" {
+		button.Style = slack.StyleDanger
+		button.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject(slack.PlainTextType, messageTo(user, text, nil), true, false),
+			slack.NewTextBlockObject(slack.PlainTextType, messageTo(user, confirm, nil), true, false),
+			slack.NewTextBlockObject(slack.PlainTextType, messageTo(user, "homeConfirmButton", nil), true, false),
+			slack.NewTextBlockObject(slack.PlainTextType, messageTo(user, "homeCancelButton", nil), true, false),
+		)
+	}
+
+	return button
+}
+
+func isPoolCandidate(user string) (bool, error) {
+	var candidate TwinLunchCandidate
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Get(ctx, twinLunchCandidateKey(user), &candidate)
+	}); errors.Is(err, datastore.ErrNoSuchEntity) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error reading candidate from datastore: %w", err)
+	}
+
+	return true, nil
+}
+
+// homeActionCommand turns a click on an App Home button into the command it stands for.
+func homeActionCommand(callback slack.InteractionCallback) (slack.SlashCommand, bool) {
+	if callback.Type != slack.InteractionTypeBlockActions || len(callback.ActionCallback.BlockActions) != 1 {
+		return slack.SlashCommand{}, false
+	}
+
+	var name, ok = homeActions[callback.ActionCallback.BlockActions[0].ActionID]
+	if !ok {
+		return slack.SlashCommand{}, false
+	}
+
+	return slack.SlashCommand{
+		Command:  name,
+		TeamID:   callback.Team.ID,
+		APIAppID: callback.APIAppID,
+		UserID:   callback.User.ID,
+		UserName: callback.User.Name,
+	}, true
+}
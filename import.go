@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+type importedPair struct {
+	Line         int
+	User1, User2 string
+}
+
+// handleImportCommand creates the pairings listed one per line, either all of them or none.
+// The whole batch is validated first, and every invalid line is reported.
+func handleImportCommand(command slack.SlashCommand) {
+	var input, dryRun = parseDryRun(command.Text)
+	var pairs []importedPair
+	var errs []string
+	var lines = make(map[string]int)
+
+	var lineError = func(line int, text string) {
+		errs = append(errs, messageTo(command.UserID, "importLineError", messageData{"Line": line, "Error": text}))
+	}
+
+	for i, text := range strings.Split(input, "\n") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		var line = i + 1
+		var matches = userRegexp.FindAllStringSubmatch(text, -1)
+
+		if len(matches) != 2 {
+			lineError(line, messageTo(command.UserID, "importInvalidLine", messageData{"Count": len(matches)}))
+			continue
+		}
+
+		var pair = importedPair{line, matches[0][1], matches[1][1]}
+
+		if pair.User1 == pair.User2 {
+			lineError(line, messageTo(command.UserID, "addSameUser", nil))
+			continue
+		}
+
+		var valid = true
+		for _, user := range []string{pair.User1, pair.User2} {
+			if first, ok := lines[user]; ok {
+				lineError(line, messageTo(command.UserID, "importUserTwice", messageData{"User": user, "First": first}))
+				valid = false
+				continue
+			}
+			lines[user] = line
+
+			if _, ok := twinLunches.Get(user); ok {
+				lineError(line, messageTo(command.UserID, "alreadyPaired", messageData{"User": user}))
+				valid = false
+			}
+		}
+		if !valid {
+			continue
+		}
+
+		if exclusion, err := getExclusion(pair.User1, pair.User2); err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		} else if exclusion != nil {
+			lineError(line, messageTo(command.UserID, "excludedPair", messageData{"User1": pair.User1, "User2": pair.User2, "Reason": exclusion.Reason}))
+			continue
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	if len(pairs) == 0 && len(errs) == 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "importUsage", nil))
+		return
+	}
+
+	var users = make([]string, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		users = append(users, pair.User1, pair.User2)
+	}
+	inactive, err := inactiveUsers(users)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userInfoError", nil))
+		return
+	}
+	for _, user := range inactive {
+		lineError(lines[user], messageTo(command.UserID, "userInactive", messageData{"User": user}))
+	}
+
+	if len(errs) != 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "importInvalid", messageData{"Errors": errs}))
+		return
+	}
+
+	var warnings []string
+	for _, pair := range pairs {
+		var pairWarnings, err = pairingWarnings(command.UserID, pair.User1, pair.User2)
+		if err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		}
+		warnings = append(warnings, pairWarnings...)
+	}
+
+	if dryRun {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "imported", messageData{"Imported": pairs, "Warnings": warnings, "DryRun": true}))
+		return
+	}
+
+	var created = make([]*TwinLunch, 0, len(pairs))
+	for _, pair := range pairs {
+		created = append(created, newTwinLunch([]string{pair.User1, pair.User2}, 0, command.UserID))
+	}
+
+	if err := saveImportedTwinLunches(created); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	for _, twinLunch := range created {
+		onTwinLunchCreated(twinLunch, command.UserID)
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "imported", messageData{"Imported": pairs, "Warnings": warnings, "DryRun": false}))
+}
+
+// saveImportedTwinLunches writes the twin lunches in a single transaction, so that either all or none are saved.
+func saveImportedTwinLunches(created []*TwinLunch) error {
+	var keys, err = allocateKeys("TwinLunch", twinLunchListKey, len(created))
+	if err != nil {
+		return err
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return runInTransaction(ctx, func(tx datastoreTransaction) error {
+			if _, err := tx.PutMulti(keys, created); err != nil {
+				return fmt.Errorf("error writing keys in datastore: %w", err)
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	for i, twinLunch := range created {
+		twinLunch.Key = keys[i]
+	}
+
+	return nil
+}
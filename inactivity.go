@@ -0,0 +1,44 @@
+package main
+
+import (
+	"time"
+)
+
+var (
+	// inactivityReplyAfter is the partner inactivity duration after which the sender is told, zero disables it
+	inactivityReplyAfter time.Duration
+
+	startedAt time.Time
+
+	// lastActivity and inactivityNotified are only accessed from the run loop
+	lastActivity       = make(map[string]time.Time)
+	inactivityNotified = make(map[string]time.Time)
+)
+
+// notifyInactivePartner tells user that their partners haven't written for a while.
+// Activity is based on the messages sent to the bot, not on presence, so an offline but active partner isn't reported.
+func notifyInactivePartner(twinLunch *TwinLunch, user string) {
+	if inactivityReplyAfter == 0 {
+		return
+	}
+
+	var since = twinLunch.CreatedAt
+	if since.Before(startedAt) {
+		since = startedAt
+	}
+	for _, partner := range twinLunch.Partners(user) {
+		if last, ok := lastActivity[partner]; ok && last.After(since) {
+			since = last
+		}
+	}
+
+	var now = time.Now()
+
+	if now.Sub(since) < inactivityReplyAfter || now.Sub(inactivityNotified[user]) < inactivityReplyAfter {
+		return
+	}
+
+	inactivityNotified[user] = now
+
+	sendNotificationToUser(user, optionalMessage, messageTo(user, "inactivePartner", nil))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// introRetryInterval is the period of the pending intros sweep, zero disables it
+	introRetryInterval = 15 * time.Minute
+
+	// introsInFlight prevents sending an intro twice when a retry is triggered during a send
+	introsInFlightMu sync.Mutex
+	introsInFlight   = make(map[string]struct{})
+)
+
+func startIntro(user string) bool {
+	introsInFlightMu.Lock()
+	defer introsInFlightMu.Unlock()
+
+	if _, ok := introsInFlight[user]; ok {
+		return false
+	}
+	introsInFlight[user] = struct{}{}
+	return true
+}
+
+func endIntro(user string) {
+	introsInFlightMu.Lock()
+	defer introsInFlightMu.Unlock()
+
+	delete(introsInFlight, user)
+}
+
+func setPendingIntro(user string, pending bool) {
+	if getTwinLunchUser(user).PendingIntro == pending {
+		return
+	}
+
+	if err := updateTwinLunchUser(user, func(twinLunchUser *TwinLunchUser) {
+		twinLunchUser.PendingIntro = pending
+	}); err != nil {
+		logger.Println(err)
+	}
+}
+
+// retryPendingIntro sends again the intro of user if it failed, it must be called from the run loop.
+func retryPendingIntro(user string) {
+	if !getTwinLunchUser(user).PendingIntro {
+		return
+	}
+
+	var twinLunch, ok = twinLunches.Get(user)
+	if !ok {
+		setPendingIntro(user, false)
+		return
+	}
+
+	logger.Printf("retrying intro for user %s", user)
+
+	sendIntroToUser(twinLunch, user)
+}
+
+func retryPendingIntros() {
+	var users []string
+
+	twinLunchUsersMu.Lock()
+	for user, twinLunchUser := range twinLunchUsers {
+		if twinLunchUser.PendingIntro {
+			users = append(users, user)
+		}
+	}
+	twinLunchUsersMu.Unlock()
+
+	for _, user := range users {
+		retryPendingIntro(user)
+	}
+}
+
+// holdForPendingIntro tells user that their message isn't relayed if the intro of a partner failed.
+// Intros being sent don't need to be waited for, as they are queued before the relayed messages.
+func holdForPendingIntro(twinLunch *TwinLunch, user string) bool {
+	for _, partner := range twinLunch.Partners(user) {
+		if getTwinLunchUser(partner).PendingIntro {
+			sendBotMessageToUser(user, messageTo(user, "partnerIntroPending", nil))
+			return true
+		}
+	}
+	return false
+}
+
+// notifyIntroFailed tells the admin who created the pairing that the intro of user can't be delivered,
+// or the configured admins if the pairing creator is unknown. It may be called outside of the run loop.
+func notifyIntroFailed(twinLunch *TwinLunch, user string) {
+	logger.Printf("warning: intro of user %s can't be delivered", user)
+
+	if twinLunch.CreatedBy != "" {
+		sendBotMessageToUser(twinLunch.CreatedBy, messageTo(twinLunch.CreatedBy, "introFailed", messageData{"User": user}))
+		return
+	}
+	for admin := range configuredAdmins {
+		sendBotMessageToUser(admin, messageTo(admin, "introFailed", messageData{"User": user}))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestRetryPendingIntro(t *testing.T) {
+	var intro = introMessage(&TwinLunch{Round: 1}, "U1")
+
+	var tests = []struct {
+		name  string
+		retry func()
+	}{
+		{"sweep", retryPendingIntros},
+		{"message", func() {
+			handleMessage(&slackevents.MessageEvent{Channel: dmChannel("U1"), User: "U1", ChannelType: slack.TYPE_IM, Text: "hello"})
+		}},
+		{"command", func() {
+			handleCommand(slack.SlashCommand{Command: "/twinlunch-prefs", UserID: "U1"})
+		}},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var fs, _ = setupFakes(t)
+			fs.failNext("OpenConversation", errors.New("ratelimited"))
+
+			if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+				t.Fatal(err)
+			}
+			deliveries.Wait()
+
+			if !getTwinLunchUser("U1").PendingIntro {
+				t.Fatal("intro of U1 isn't pending after a failure")
+			}
+			checkMessages(t, fs, map[string][]string{dmChannel("U1"): nil, dmChannel("U2"): {intro}})
+
+			test.retry()
+			deliveries.Wait()
+
+			if getTwinLunchUser("U1").PendingIntro {
+				t.Error("intro of U1 is still pending after the retry")
+			}
+			// a delivered intro isn't sent again
+			retryPendingIntros()
+			deliveries.Wait()
+
+			for _, user := range []string{"U1", "U2"} {
+				var texts = fs.messagesTo(dmChannel(user))
+				var intros = 0
+				for _, text := range texts {
+					if strings.Contains(text, intro) {
+						intros++
+					}
+				}
+				if len(texts) == 0 || !strings.Contains(texts[0], intro) || intros != 1 {
+					t.Errorf("messages to %s = %q, want one intro first", user, texts)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	listPageSize = 50
+	// usersInfoBatchSize is the number of users asked at once to users.info
+	usersInfoBatchSize = 30
+)
+
+// displayNames caches the names used to sort the list, it is only accessed from the run loop
+var displayNames = make(map[string]string)
+
+// getDisplayNames returns the lowercased display names of users, falling back to their IDs.
+func getDisplayNames(users []string) map[string]string {
+	var missing []string
+	for _, user := range users {
+		if _, ok := displayNames[user]; !ok {
+			missing = append(missing, user)
+		}
+	}
+
+	for start := 0; start < len(missing); start += usersInfoBatchSize {
+		var end = start + usersInfoBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		var infos, err = slackClient.GetUsersInfo(missing[start:end]...)
+		if err != nil {
+			logger.Printf("error getting users info: %s", err)
+			break
+		}
+
+		for _, info := range *infos {
+			var name = info.Profile.DisplayName
+			if name == "" {
+				name = info.RealName
+			}
+			if name == "" {
+				name = info.Name
+			}
+			displayNames[info.ID] = strings.ToLower(name)
+		}
+	}
+
+	var names = make(map[string]string, len(users))
+	for _, user := range users {
+		if name, ok := displayNames[user]; ok {
+			names[user] = name
+		} else {
+			names[user] = user
+		}
+	}
+	return names
+}
+
+// sortedPairs returns the pairings sorted by display name, members of each pairing are sorted too.
+func sortedPairs(list []*TwinLunch) []pairData {
+	var users []string
+	for _, twinLunch := range list {
+		users = append(users, twinLunch.Members()...)
+	}
+	var names = getDisplayNames(users)
+
+	var less = func(user1 string, user2 string) bool {
+		if names[user1] != names[user2] {
+			return names[user1] < names[user2]
+		}
+		return user1 < user2
+	}
+
+	var pairs = make([]pairData, 0, len(list))
+	for _, twinLunch := range list {
+		var members = twinLunch.Members()
+		sort.Slice(members, func(i, j int) bool { return less(members[i], members[j]) })
+		pairs = append(pairs, newPairData(members))
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return less(pairs[i].User1, pairs[j].User1) })
+
+	return pairs
+}
+
+// handleListCommand sends all the pages of the list, or only the given page.
+func handleListCommand(command slack.SlashCommand) {
+	var page int
+	if text := strings.TrimSpace(command.Text); text != "" {
+		var err error
+		if page, err = strconv.Atoi(text); err != nil || page < 1 {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "listInvalidPage", nil))
+			return
+		}
+	}
+
+	var list = twinLunches.List()
+	if len(list) == 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "noTwinLunches", nil))
+		return
+	}
+
+	var pages = (len(list) + listPageSize - 1) / listPageSize
+	if page > pages {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "listPageOutOfRange", messageData{"Pages": pages}))
+		return
+	}
+
+	var pairs = sortedPairs(list)
+
+	var first, last = 1, pages
+	if page != 0 {
+		first, last = page, page
+	}
+
+	for i := first; i <= last; i++ {
+		var end = i * listPageSize
+		if end > len(pairs) {
+			end = len(pairs)
+		}
+
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "list", messageData{
+			"Count": len(pairs),
+			"Page":  i,
+			"Pages": pages,
+			"Pairs": pairs[(i-1)*listPageSize : end],
+		}))
+	}
+}
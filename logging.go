@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// logJSON writes one JSON object per line, for Cloud Logging, it is set by LOG_FORMAT=json
+	logJSON bool
+
+	logMu sync.Mutex
+)
+
+// fieldLogger is a logger whose lines carry key=value fields, it can be given to the slack clients as a logger.
+type fieldLogger struct {
+	out    io.Writer
+	prefix string
+	fields []logField
+}
+
+type logField struct {
+	key   string
+	value interface{}
+}
+
+func newFieldLogger(out io.Writer, prefix string) *fieldLogger {
+	return &fieldLogger{out: out, prefix: prefix}
+}
+
+// With returns a logger adding the fields to every line, keysAndValues alternates keys and values.
+func (l *fieldLogger) With(keysAndValues ...interface{}) *fieldLogger {
+	var fields = make([]logField, len(l.fields), len(l.fields)+len(keysAndValues)/2)
+	copy(fields, l.fields)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields = append(fields, logField{fmt.Sprint(keysAndValues[i]), keysAndValues[i+1]})
+	}
+
+	return &fieldLogger{l.out, l.prefix, fields}
+}
+
+func (l *fieldLogger) Print(v ...interface{}) {
+	l.Output(2, fmt.Sprint(v...))
+}
+
+func (l *fieldLogger) Printf(format string, v ...interface{}) {
+	l.Output(2, fmt.Sprintf(format, v...))
+}
+
+func (l *fieldLogger) Println(v ...interface{}) {
+	l.Output(2, fmt.Sprintln(v...))
+}
+
+func (l *fieldLogger) Fatal(v ...interface{}) {
+	l.output(2, "CRITICAL", fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+func (l *fieldLogger) Fatalf(format string, v ...interface{}) {
+	l.output(2, "CRITICAL", fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+// Output writes a line like log.Logger.Output, calldepth counts from the caller of Output.
+func (l *fieldLogger) Output(calldepth int, s string) error {
+	return l.output(calldepth+1, logSeverity(s), s)
+}
+
+func (l *fieldLogger) output(calldepth int, severity string, s string) error {
+	var now = time.Now()
+	var source = "???:0"
+	if _, file, line, ok := runtime.Caller(calldepth); ok {
+		source = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	s = strings.TrimSuffix(s, "\n")
+
+	var buf bytes.Buffer
+	if logJSON {
+		l.writeJSON(&buf, now, severity, source, s)
+	} else {
+		l.writeText(&buf, now, source, s)
+	}
+	buf.WriteByte('\n')
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	var _, err = l.out.Write(buf.Bytes())
+	return err
+}
+
+// writeText keeps the format of log.LstdFlags|log.Lshortfile, with the fields after the message.
+func (l *fieldLogger) writeText(buf *bytes.Buffer, now time.Time, source string, s string) {
+	buf.WriteString(l.prefix)
+	buf.WriteString(now.Format("2006/01/02 15:04:05 "))
+	buf.WriteString(source)
+	buf.WriteString(": ")
+	buf.WriteString(s)
+
+	for _, field := range l.fields {
+		var value = fmt.Sprint(field.value)
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(buf, " %s=%s", field.key, value)
+	}
+}
+
+// writeJSON uses the field names recognized by Cloud Logging, the fields are written in order after the message.
+func (l *fieldLogger) writeJSON(buf *bytes.Buffer, now time.Time, severity string, source string, s string) {
+	var write = func(key string, value interface{}) {
+		var encoded, err = json.Marshal(value)
+		if err != nil {
+			encoded, _ = json.Marshal(fmt.Sprint(value))
+		}
+		var encodedKey, _ = json.Marshal(key)
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+
+	buf.WriteByte('{')
+	write("severity", severity)
+	write("time", now.Format(time.RFC3339Nano))
+	write("logger", strings.TrimSuffix(l.prefix, ": "))
+	write("source", source)
+	write("message", s)
+	for _, field := range l.fields {
+		write(field.key, field.value)
+	}
+	buf.WriteByte('}')
+}
+
+// logSeverity relies on the lines starting with "warning:" or "error ...".
+func logSeverity(s string) string {
+	switch {
+	case strings.HasPrefix(s, "warning"):
+		return "WARNING"
+	case strings.HasPrefix(s, "error"):
+		return "ERROR"
+	}
+	return "INFO"
+}
+
+// logUser hashes a user ID, so that the lines of a user can be correlated without telling who the partners are.
+func logUser(user string) string {
+	if user == "" {
+		return ""
+	}
+	var sum = sha256.Sum256([]byte(user))
+	return hex.EncodeToString(sum[:6])
+}
+
+// logUsers hashes several user IDs, as a comma separated list.
+func logUsers(users ...string) string {
+	var hashed = make([]string, 0, len(users))
+	for _, user := range users {
+		hashed = append(hashed, logUser(user))
+	}
+	return strings.Join(hashed, ",")
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"cloud.google.com/go/datastore"
@@ -22,22 +26,86 @@ import (
 )
 
 var (
-	logger = log.New(os.Stdout, "main: ", log.Lshortfile|log.LstdFlags)
+	logger = newFieldLogger(os.Stdout, "main: ")
 	debug  bool
+	// originTags is debug-only, it prefixes messages with [RELAY] or [BOT] and the resolved IDs
+	originTags bool
 
-	userRegexp = regexp.MustCompile(`<@([^\|]+)\|[^>]+>`)
+	// userRegexp matches the mentions with or without the user name, <@U123|name> or <@U123>
+	userRegexp = regexp.MustCompile(`<@([^|>]+)(?:\|[^>]*)?>`)
 
-	twinLunches     = make(map[string]string)
+	twinLunches = newTwinLunchStore()
+	// twinLunchAdmins is only modified from the run loop after startup
 	twinLunchAdmins = make(map[string]struct{})
 
-	slackClient     *socketmode.Client
-	datastoreClient *datastore.Client
+	publicCommands = map[string]struct{}{
+		"/twinlunch-codename":   {},
+		"/twinlunch-my-history": {},
+		"/twinlunch-prefs":      {},
+		"/twinlunch-report":     {},
+		"/twinlunch-join":       {},
+		"/twinlunch-leave":      {},
+		"/twinlunch-quit":       {},
+	}
+
+	// slackClient is socketClient, and datastoreClient is Cloud Datastore, except in tests where they are fakes
+	slackClient     slackAPI
+	socketClient    *socketmode.Client
+	datastoreClient datastoreAPI
+
+	slackTeamID, slackAppID string
+	slackBotID              string
+
+	pinIntro bool
 
 	twinLunchListKey = datastore.NameKey("TwinLunchList", "default", nil)
 )
 
 type TwinLunch struct {
-	User1, User2 string
+	Key                  *datastore.Key `datastore:"__key__" json:"-"`
+	User1, User2         string
+	Emoji                string `datastore:",noindex"`
+	Codename1, Codename2 string `datastore:",noindex"`
+	CreatedAt            time.Time
+	Messages             int `datastore:",noindex"`
+	Round                int
+	// Slot is the table number of pairings created with /twinlunch-add-numbered, zero otherwise
+	Slot int `datastore:",noindex"`
+	// Disclaimed1 and Disclaimed2 are set once the relay disclaimer has been sent to each user
+	Disclaimed1, Disclaimed2 bool `datastore:",noindex"`
+	// Others are the members of groups besides User1 and User2, with their codenames and disclaimer flags
+	Others           []string `datastore:",noindex"`
+	OthersCodenames  []string `datastore:",noindex"`
+	OthersDisclaimed []bool   `datastore:",noindex"`
+	// Aliases are the emojis of the members' aliases, in the order of Members, empty for older pairings
+	Aliases []string `datastore:",noindex"`
+	// CreatedBy is the admin who created the pairing, empty for scheduled pairings and older ones
+	CreatedBy string `datastore:",noindex"`
+}
+
+// Members returns the users of the pairing, User1 and User2 first.
+func (twinLunch *TwinLunch) Members() []string {
+	return append([]string{twinLunch.User1, twinLunch.User2}, twinLunch.Others...)
+}
+
+// Partners returns the members of the pairing besides user.
+func (twinLunch *TwinLunch) Partners(user string) []string {
+	var partners = make([]string, 0, 1+len(twinLunch.Others))
+	for _, member := range twinLunch.Members() {
+		if member != user {
+			partners = append(partners, member)
+		}
+	}
+	return partners
+}
+
+func (twinLunch *TwinLunch) memberIndex(user string) int {
+	for i, member := range twinLunch.Members() {
+		if member == user {
+			return i
+		}
+	}
+	return -1
 }
 
 type TwinLunchList struct{}
@@ -47,10 +115,134 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	switch format := os.Getenv("LOG_FORMAT"); format {
+	case "", "text":
+	case "json":
+		logJSON = true
+	default:
+		logger.Fatalf("invalid LOG_FORMAT %q", format)
+	}
+
 	debug = os.Getenv("DEBUG") == "true"
+	originTags = debug
+
+	slackTeamID, slackAppID = os.Getenv("SLACK_TEAM_ID"), os.Getenv("SLACK_APP_ID")
+
+	pinIntro = os.Getenv("PIN_INTRO") == "true"
+
+	cleanupOnRemove = os.Getenv("CLEANUP_ON_REMOVE") == "true"
+
+	datastoreTimeout = getEnvDuration("DATASTORE_TIMEOUT", datastoreTimeout)
+	datastoreRetries = getEnvInt("DATASTORE_RETRIES", datastoreRetries)
+	var maxTransactions = getEnvInt("DATASTORE_MAX_TRANSACTIONS", cap(transactionSlots))
+	if maxTransactions < 1 {
+		logger.Fatal("invalid DATASTORE_MAX_TRANSACTIONS: must be at least 1")
+	}
+	transactionSlots = make(chan struct{}, maxTransactions)
+	slackTimeout = getEnvDuration("SLACK_TIMEOUT", slackTimeout)
+	slackReconnectRetries = getEnvInt("SLACK_RECONNECT_RETRIES", slackReconnectRetries)
+	slackReconnectMaxBackoff = getEnvDuration("SLACK_RECONNECT_MAX_BACKOFF", slackReconnectMaxBackoff)
+	eventDedupWindow = getEnvDuration("EVENT_DEDUP_WINDOW", eventDedupWindow)
+	watchdogThreshold = getEnvDuration("WATCHDOG_THRESHOLD", watchdogThreshold)
+
+	graphMinCohort = getEnvInt("GRAPH_MIN_COHORT", graphMinCohort)
+	maxTwinLunchesPerUser = getEnvInt("MAX_TWIN_LUNCHES_PER_USER", 0)
+	repeatAvoidRounds = getEnvInt("REPEAT_AVOID_ROUNDS", 0)
+	if groupSize = getEnvInt("GROUP_SIZE", groupSize); groupSize < 2 {
+		logger.Fatalf("invalid GROUP_SIZE: %d", groupSize)
+	}
+	programStart = getEnvDate("PROGRAM_START")
+	if programEnd = getEnvDate("PROGRAM_END"); !programEnd.IsZero() {
+		// the end date is inclusive
+		programEnd = programEnd.AddDate(0, 0, 1)
+	}
+
+	inactivityReplyAfter = getEnvDuration("INACTIVITY_REPLY_AFTER", 0)
+	repairCooldown = getEnvDuration("REPAIR_COOLDOWN", 0)
+	introRetryInterval = getEnvDuration("INTRO_RETRY_INTERVAL", introRetryInterval)
+	if twinLunchSaveInterval = getEnvDuration("TWIN_LUNCH_SAVE_INTERVAL", twinLunchSaveInterval); twinLunchSaveInterval <= 0 {
+		logger.Fatalf("invalid TWIN_LUNCH_SAVE_INTERVAL: %s", twinLunchSaveInterval)
+	}
+
+	unpairedReplyOnce = os.Getenv("UNPAIRED_REPLY") == "once"
+	unpairedReminderAfter = getEnvDuration("UNPAIRED_REMINDER_AFTER", unpairedReminderAfter)
+
+	pairingSummary = os.Getenv("PAIRING_SUMMARY") == "true"
+	nextRoundURL = os.Getenv("NEXT_ROUND_URL")
+
+	switch mode := os.Getenv("RELAY_DISCLAIMER"); mode {
+	case "", relayDisclaimerOff:
+	case relayDisclaimerAlways, relayDisclaimerFirst:
+		relayDisclaimer = mode
+	default:
+		logger.Fatalf("invalid RELAY_DISCLAIMER %q", mode)
+	}
+
+	switch style := os.Getenv("RELAY_STYLE"); style {
+	case "", relayStylePlain:
+	case relayStyleQuote, relayStyleMarkers:
+		relayStyle = style
+	default:
+		logger.Fatalf("invalid RELAY_STYLE %q", style)
+	}
+	relayPrefix = os.Getenv("RELAY_PREFIX")
+	relaySuffix = os.Getenv("RELAY_SUFFIX")
+
+	relayRateLimit = getEnvInt("RELAY_RATE_LIMIT", relayRateLimit)
+	if relayRateBurst = getEnvInt("RELAY_RATE_BURST", relayRateBurst); relayRateBurst < 1 {
+		logger.Fatalf("invalid RELAY_RATE_BURST: %d", relayRateBurst)
+	}
+
+	maxForwardedFileSize = getEnvInt("MAX_FORWARDED_FILE_SIZE", maxForwardedFileSize)
+	scrubFileNames = os.Getenv("SCRUB_FILE_NAMES") != "false"
+
+	reportChannel = os.Getenv("REPORT_CHANNEL")
+	reportCooldown = getEnvDuration("REPORT_COOLDOWN", reportCooldown)
+
+	topicChannel = os.Getenv("TOPIC_CHANNEL")
+	topicClearOnClear = os.Getenv("TOPIC_CLEAR") == "true"
+	topicUpdateDelay = getEnvDuration("TOPIC_UPDATE_DELAY", topicUpdateDelay)
 
+	if tz := os.Getenv("PAIRING_TIMEZONE"); tz != "" {
+		var err error
+		if pairingLocation, err = time.LoadLocation(tz); err != nil {
+			logger.Fatalf("invalid PAIRING_TIMEZONE: %s", err)
+		}
+	}
+	if spec := os.Getenv("PAIRING_SCHEDULE"); spec != "" {
+		var err error
+		if pairingSchedule, err = parseCronSchedule(spec); err != nil {
+			logger.Fatalf("invalid PAIRING_SCHEDULE: %s", err)
+		}
+	}
+
+	workflowWebhookURL = os.Getenv("WORKFLOW_WEBHOOK_URL")
+	workflowWebhookOnly = os.Getenv("WORKFLOW_WEBHOOK_MODE") == "replace"
+
+	if lang := os.Getenv("LANG"); lang != "" {
+		setMessageLanguage(lang)
+	}
+	if path := os.Getenv("MESSAGE_TEMPLATES_FILE"); path != "" {
+		loadMessageTemplates(path)
+	}
+
+	rand.Seed(time.Now().UnixNano())
+
+	var ctx, stop = signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if adminAPI = os.Getenv("ADMIN_API") == "true"; adminAPI {
+		registerAdminAPI()
+	}
+
+	registerHealthChecks()
+
+	// warmup may be requested more than once per instance, later requests wait for the first one to start
+	var startOnce sync.Once
 	http.HandleFunc("/_ah/warmup", func(w http.ResponseWriter, r *http.Request) {
-		start(r.Context())
+		startOnce.Do(func() {
+			start(ctx, r.Context())
+		})
 	})
 
 	var port = os.Getenv("PORT")
@@ -58,323 +250,1057 @@ func main() {
 		port = "8080"
 	}
 
+	personaEmojiPerPair = os.Getenv("PERSONA_EMOJI_MODE") == "pair"
+	for _, emoji := range strings.Split(os.Getenv("PERSONA_EMOJIS"), ",") {
+		if emoji = strings.Trim(strings.TrimSpace(emoji), ":"); emoji == "" {
+			continue
+		}
+		if !emojiRegexp.MatchString(emoji) {
+			logger.Printf("ignoring invalid persona emoji %q", emoji)
+			continue
+		}
+		personaEmojis = append(personaEmojis, emoji)
+	}
+
 	for _, twinLunchAdmin := range strings.Split(os.Getenv("TWIN_LUNCH_ADMINS"), ",") {
 		if twinLunchAdmin == "" {
 			continue
 		}
-		twinLunchAdmins[twinLunchAdmin] = struct{}{}
+		configuredAdmins[twinLunchAdmin] = struct{}{}
 	}
 
-	logger.Printf("listening on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		logger.Fatal(err)
-	}
+	broadcastInterval = getEnvDuration("BROADCAST_INTERVAL", broadcastInterval)
+
+	shutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", shutdownTimeout)
+
+	var server = &http.Server{Addr: ":" + port}
+
+	go func() {
+		logger.Printf("listening on port %s", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	shutdown(server)
 }
 
-func start(ctx context.Context) {
+// start connects to slack and starts the event loops, which stop when ctx is done.
+// loadCtx is only used to load the state from datastore.
+func start(ctx context.Context, loadCtx context.Context) {
 	logger.Println("received warmup request, starting...")
 
-	var secrets, err = getSecrets(ctx, "SLACK_BOT_TOKEN", "SLACK_APP_TOKEN")
+	startedAt = time.Now()
+
+	var secrets, err = getSecrets(loadCtx, "SLACK_BOT_TOKEN", "SLACK_APP_TOKEN")
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 
-	slackClient = socketmode.New(
+	socketClient = socketmode.New(
 		slack.New(
 			secrets["SLACK_BOT_TOKEN"],
+			slack.OptionHTTPClient(&http.Client{Timeout: slackTimeout}),
 			slack.OptionDebug(debug),
-			slack.OptionLog(log.New(os.Stdout, "slack: ", log.Lshortfile|log.LstdFlags)),
+			slack.OptionLog(newFieldLogger(os.Stdout, "slack: ")),
 			slack.OptionAppLevelToken(secrets["SLACK_APP_TOKEN"]),
 		),
 		socketmode.OptionDebug(debug),
-		socketmode.OptionLog(log.New(os.Stdout, "socketmode: ", log.Lshortfile|log.LstdFlags)),
+		socketmode.OptionLog(newFieldLogger(os.Stdout, "socketmode: ")),
 	)
 
-	if datastoreClient, err = datastore.NewClient(context.Background(), ""); err != nil {
+	slackClient = socketClient
+
+	var auth, errAuth = slackClient.AuthTest()
+	if errAuth != nil {
+		logger.Fatalf("error testing slack authentication: %s", errAuth)
+	}
+	if slackTeamID == "" {
+		slackTeamID = auth.TeamID
+	}
+	slackBotID = auth.BotID
+
+	checkScopes(secrets["SLACK_BOT_TOKEN"])
+
+	cloudDatastoreClient, err := datastore.NewClient(context.Background(), "")
+	if err != nil {
 		logger.Fatal(err)
 	}
+	datastoreClient = cloudDatastore{cloudDatastoreClient}
 
-	loadTwinLunches(ctx)
+	if err := loadTwinLunches(loadCtx); err != nil {
+		logger.Fatal(err)
+	}
+	loadTwinLunchUsers(loadCtx)
+	loadTwinLunchConfig(loadCtx)
+	loadTwinLunchAdmins(loadCtx)
 
 	var messages = make(chan *slackevents.MessageEvent)
 	var filteredMessages = make(chan *slackevents.MessageEvent)
 	var commands = make(chan slack.SlashCommand)
+	var reactions = make(chan reactionChange)
+	var homes = make(chan string)
 
-	go receiveEvents(slackClient, messages, commands)
+	var pairings chan time.Time
+	if pairingSchedule != nil {
+		pairings = make(chan time.Time)
+		go runPairingSchedule(ctx, pairings)
+	}
+
+	go receiveEvents(ctx, socketClient.Events, socketClient, messages, commands, reactions, homes)
 	go filterMessages(messages, filteredMessages)
-	go run(filteredMessages, commands)
+	loops.Add(1)
+	go run(filteredMessages, commands, reactions, homes, pairings)
+	go runWatchdog(ctx)
+
+	go runSlackClient(ctx)
 
-	go runSlackClient()
+	setStartCompleted()
 }
 
-func receiveEvents(client *socketmode.Client, messages chan<- *slackevents.MessageEvent, commands chan<- slack.SlashCommand) {
-	for clientEvt := range client.Events {
+// receiveEvents closes its output channels when ctx is done, so that the run loop can drain them and return.
+// The events are acknowledged with client.
+func receiveEvents(ctx context.Context, events <-chan socketmode.Event, client eventAcknowledger, messages chan<- *slackevents.MessageEvent, commands chan<- slack.SlashCommand, reactions chan<- reactionChange, homes chan<- string) {
+	defer close(messages)
+	defer close(commands)
+	defer close(reactions)
+	defer close(homes)
+
+	var dedup = newEventDeduplicator()
+
+	for {
+		var clientEvt socketmode.Event
+
+		select {
+		case <-ctx.Done():
+			setSlackConnected(false)
+			return
+		case clientEvt = <-events:
+		}
+
+		var eventLogger = logger.With("event", clientEvt.Type)
+
 		switch clientEvt.Type {
 
+		case socketmode.EventTypeConnected:
+			setSlackConnected(true)
+
+		// slack asks to reconnect with a disconnect event, the client is connecting again until the next connected event
+		case socketmode.EventTypeConnecting, socketmode.EventTypeConnectionError, socketmode.EventTypeDisconnect:
+			setSlackConnected(false)
+
 		case socketmode.EventTypeEventsAPI:
 			var outerEvt = clientEvt.Data.(slackevents.EventsAPIEvent)
+			eventLogger = eventLogger.With("outer_event", outerEvt.Type)
 
 			if outerEvt.Type != slackevents.CallbackEvent {
-				logger.Println("ignoring slack outer event", outerEvt)
+				eventLogger.Println("ignoring slack outer event", outerEvt)
 				continue
 			}
 
 			var innerEvt = outerEvt.InnerEvent
-			if innerEvt.Type != slackevents.Message {
-				logger.Println("ignoring slack inner event", innerEvt)
+			eventLogger = eventLogger.With("inner_event", innerEvt.Type)
+
+			if innerEvt.Type != slackevents.Message && innerEvt.Type != slackevents.ReactionAdded && innerEvt.Type != slackevents.ReactionRemoved && innerEvt.Type != slackevents.AppHomeOpened {
+				eventLogger.Println("ignoring slack inner event", innerEvt)
 				continue
 			}
 
-			messages <- innerEvt.Data.(*slackevents.MessageEvent)
-
+			// acking first avoids retries while the message is handled
 			client.Ack(*clientEvt.Request)
 
+			if callbackEvt, ok := outerEvt.Data.(*slackevents.EventsAPICallbackEvent); ok && callbackEvt.EventID != "" {
+				if dedup.seen(callbackEvt.EventID) {
+					eventLogger.With("event_id", callbackEvt.EventID).Println("ignoring retried event")
+					continue
+				}
+			}
+
+			switch event := innerEvt.Data.(type) {
+			case *slackevents.MessageEvent:
+				messages <- event
+
+			case *slackevents.ReactionAddedEvent:
+				reactions <- reactionChange{true, event.User, event.Reaction, event.Item.Channel, event.Item.Timestamp}
+
+			case *slackevents.ReactionRemovedEvent:
+				reactions <- reactionChange{false, event.User, event.Reaction, event.Item.Channel, event.Item.Timestamp}
+
+			case *slackevents.AppHomeOpenedEvent:
+				if event.Tab == "home" {
+					homes <- event.User
+				}
+			}
+
 		case socketmode.EventTypeSlashCommand:
-			commands <- clientEvt.Data.(slack.SlashCommand)
+			var command = clientEvt.Data.(slack.SlashCommand)
+
+			if command.TeamID != slackTeamID || (slackAppID != "" && command.APIAppID != slackAppID) {
+				eventLogger.With("command", command.Command, "user", logUser(command.UserID)).Printf("warning: ignoring slash command %s from team %s and app %s", command.Command, command.TeamID, command.APIAppID)
+				client.Ack(*clientEvt.Request)
+				continue
+			}
+
+			// the answer comes in a direct message, the ephemeral acknowledgement tells the command was received meanwhile.
+			// It is sent first, the run loop may be busy for longer than the slack deadline.
+			client.Ack(*clientEvt.Request, map[string]interface{}{
+				"response_type": slack.ResponseTypeEphemeral,
+				"text":          messageTo(command.UserID, "commandReceived", nil),
+			})
 
+			commands <- command
+
+		case socketmode.EventTypeInteractive:
 			client.Ack(*clientEvt.Request)
+
+			var command, ok = homeActionCommand(clientEvt.Data.(slack.InteractionCallback))
+			if !ok {
+				eventLogger.Println("ignoring slack interaction", clientEvt.Data)
+				continue
+			}
+
+			if command.TeamID != slackTeamID || (slackAppID != "" && command.APIAppID != slackAppID) {
+				eventLogger.With("command", command.Command, "user", logUser(command.UserID)).Printf("warning: ignoring home action %s from team %s and app %s", command.Command, command.TeamID, command.APIAppID)
+				continue
+			}
+
+			commands <- command
 		}
 	}
 }
 
 func filterMessages(in <-chan *slackevents.MessageEvent, out chan<- *slackevents.MessageEvent) {
+	defer close(out)
+
 	for messageEvt := range in {
-		if messageEvt.BotID != "" {
-			continue
-		}
 		if messageEvt.ChannelType != slack.TYPE_IM {
 			continue
 		}
+
+		// edits and deletions of the bot's own messages, including the relayed copies, are ignored
+		switch messageEvt.SubType {
+		case slack.MsgSubTypeMessageChanged:
+			// changes without an edit are link unfurls
+			if messageEvt.Message == nil || messageEvt.Message.BotID != "" || messageEvt.Message.Edited == nil {
+				continue
+			}
+		case slack.MsgSubTypeMessageDeleted:
+			if messageEvt.PreviousMessage == nil || messageEvt.PreviousMessage.BotID != "" {
+				continue
+			}
+		default:
+			if messageEvt.BotID != "" {
+				continue
+			}
+		}
+
 		out <- messageEvt
 	}
 }
 
-func run(messages <-chan *slackevents.MessageEvent, commands <-chan slack.SlashCommand) {
-	for {
+// run handles the events until all the input channels are closed.
+// pairings is nil when scheduled pairing is disabled.
+func run(messages <-chan *slackevents.MessageEvent, commands <-chan slack.SlashCommand, reactions <-chan reactionChange, homes <-chan string, pairings <-chan time.Time) {
+	defer loops.Done()
+
+	var introRetries <-chan time.Time
+	if introRetryInterval > 0 {
+		var ticker = time.NewTicker(introRetryInterval)
+		defer ticker.Stop()
+		introRetries = ticker.C
+	}
+
+	var saves = time.NewTicker(twinLunchSaveInterval)
+	defer saves.Stop()
+	// the changes since the last save are written once the events are drained
+	defer saveUnsavedTwinLunches()
+
+	for messages != nil || commands != nil || reactions != nil || homes != nil || pairings != nil {
 		select {
-		case message := <-messages:
-			if twinLunch, ok := twinLunches[message.User]; ok {
-				forwardTwinLunchMessage(twinLunch, message.Text)
-			} else {
-				sendBotMessageToChannel(message.Channel, "Désolé tu n'as pas de Twin Lunch :crying_cat_face:", 0)
+		case message, ok := <-messages:
+			if !ok {
+				messages = nil
+				continue
+			}
+			watchdogBusy("message")
+			handleMessage(message)
+			watchdogIdle()
+
+		case command, ok := <-commands:
+			if !ok {
+				commands = nil
+				continue
+			}
+			watchdogBusy(command.Command)
+			handleCommand(command)
+			watchdogIdle()
+
+		case change, ok := <-reactions:
+			if !ok {
+				reactions = nil
+				continue
 			}
+			watchdogBusy("reaction")
+			handleReactionChange(change)
+			watchdogIdle()
 
-		case command := <-commands:
-			if _, ok := twinLunchAdmins[command.UserID]; !ok {
-				sendBotMessageToUser(command.UserID, "Désolé mais tu n'as pas les droits pour administrer les Twin Lunch :no_entry_sign:", 0)
+		case user, ok := <-homes:
+			if !ok {
+				homes = nil
+				continue
+			}
+			watchdogBusy("home")
+			handleHomeOpened(user)
+			watchdogIdle()
+
+		case request := <-adminRequests:
+			watchdogBusy("admin request")
+			handleAdminRequest(request)
+			watchdogIdle()
+
+		case _, ok := <-pairings:
+			if !ok {
+				pairings = nil
 				continue
 			}
+			watchdogBusy("scheduled pairing")
+			handleScheduledPairing()
+			watchdogIdle()
+
+		case <-introRetries:
+			watchdogBusy("intro retries")
+			retryPendingIntros()
+			watchdogIdle()
+
+		case <-saves.C:
+			watchdogBusy("twin lunches save")
+			saveUnsavedTwinLunches()
+			watchdogIdle()
+		}
+	}
+}
 
-			switch command.Command {
-			case "/twinlunch-add":
-				handleAddCommand(command)
+func handleMessage(message *slackevents.MessageEvent) {
+	switch message.SubType {
+	case slack.MsgSubTypeMessageChanged:
+		handleMessageChanged(message)
+		return
+	case slack.MsgSubTypeMessageDeleted:
+		handleMessageDeleted(message)
+		return
+	}
 
-			case "/twinlunch-remove":
-				handleRemoveCommand(command)
+	retryPendingIntro(message.User)
 
-			case "/twinlunch-list":
-				handleListCommand(command)
+	if twinLunch, ok := twinLunches.Get(message.User); ok {
+		if holdForPendingIntro(twinLunch, message.User) || !allowRelay(message.User) {
+			return
+		}
 
-			case "/twinlunch-clear":
-				handleClearCommand(command)
-			}
+		lastActivity[message.User] = time.Now()
+		for _, partner := range twinLunch.Partners(message.User) {
+			forwardTwinLunchMessage(twinLunch, partner, message)
 		}
+		twinLunch.Messages++
+		// the count is saved with the next batch, a write for each message would slow down the relay
+		unsavedTwinLunches[twinLunch] = struct{}{}
+		notifyInactivePartner(twinLunch, message.User)
+	} else {
+		replyUnpaired(message)
 	}
 }
 
-func handleAddCommand(command slack.SlashCommand) {
-	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+func handleCommand(command slack.SlashCommand) {
+	if _, ok := publicCommands[command.Command]; !ok {
+		if _, ok := twinLunchAdmins[command.UserID]; !ok {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notAdmin", nil))
+			return
+		}
+	}
 
-	if len(matches) != 2 {
-		sendBotMessageToUser(command.UserID, "Tu dois donner deux personnes pour créer un Twin Lunch", 0)
-		return
+	// a dry run changes nothing, so there is nothing to audit
+	if !isDryRun(command) {
+		recordCommandAudit(command)
 	}
+	retryPendingIntro(command.UserID)
 
-	var user1, user2 = matches[0][1], matches[1][1]
+	switch command.Command {
+	case "/twinlunch-admin-add":
+		handleAdminAddCommand(command)
+
+	case "/twinlunch-admin-remove":
+		handleAdminRemoveCommand(command)
+
+	case "/twinlunch-add":
+		handleAddCommand(command)
+
+	case "/twinlunch-remove":
+		handleRemoveCommand(command)
+
+	case "/twinlunch-list":
+		handleListCommand(command)
+
+	case "/twinlunch-clear":
+		handleClearCommand(command)
+
+	case "/twinlunch-activity":
+		handleActivityCommand(command)
+
+	case "/twinlunch-codename":
+		handleCodenameCommand(command)
+
+	case "/twinlunch-preview-as":
+		handlePreviewAsCommand(command)
+
+	case "/twinlunch-history":
+		handleHistoryCommand(command)
+
+	case "/twinlunch-my-history":
+		handleMyHistoryCommand(command)
+
+	case "/twinlunch-prefs":
+		handlePrefsCommand(command)
+
+	case "/twinlunch-exclude":
+		handleExcludeCommand(command)
+
+	case "/twinlunch-exclusions":
+		handleExclusionsCommand(command)
+
+	case "/twinlunch-inspect":
+		handleInspectCommand(command)
+
+	case "/twinlunch-broadcast":
+		handleBroadcastCommand(command)
+
+	case "/twinlunch-swap":
+		handleSwapCommand(command)
+
+	case "/twinlunch-quit":
+		handleQuitCommand(command)
 
-	if user1 == user2 {
-		sendBotMessageToUser(command.UserID, "Tu dois donner deux personnes différentes pour créer un Twin Lunch", 0)
+	case "/twinlunch-import":
+		handleImportCommand(command)
+
+	case "/twinlunch-whois":
+		handleWhoisCommand(command)
+
+	case "/twinlunch-pair-from-reaction":
+		handlePairFromReactionCommand(command)
+
+	case "/twinlunch-round":
+		handleRoundCommand(command)
+
+	case "/twinlunch-graph":
+		handleGraphCommand(command)
+
+	case "/twinlunch-add-numbered":
+		handleAddNumberedCommand(command)
+
+	case "/twinlunch-prioritize":
+		handlePrioritizeCommand(command)
+
+	case "/twinlunch-report":
+		handleReportCommand(command)
+
+	case "/twinlunch-reveal":
+		handleRevealCommand(command)
+
+	case "/twinlunch-stats":
+		handleStatsCommand(command)
+
+	case "/twinlunch-random":
+		handleRandomCommand(command)
+
+	case "/twinlunch-join":
+		handleJoinCommand(command)
+
+	case "/twinlunch-leave":
+		handleLeaveCommand(command)
+
+	case "/twinlunch-pair-pool":
+		handlePairPoolCommand(command)
+
+	case "/twinlunch-resume-pair":
+		handleResumePairCommand(command)
+	}
+}
+
+// commandLogger adds the command and the user who ran it to the lines logged by a command handler.
+func commandLogger(command slack.SlashCommand) *fieldLogger {
+	return logger.With("command", command.Command, "user", logUser(command.UserID))
+}
+
+func handleAddCommand(command slack.SlashCommand) {
+	var text, dryRun = parseDryRun(command.Text)
+	var matches = userRegexp.FindAllStringSubmatch(text, -1)
+
+	if len(matches) < 2 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "addUsage", nil)+"\n"+parsedMentions(command.UserID, matches))
 		return
 	}
 
-	if _, ok := twinLunches[user1]; ok {
-		sendBotMessageToUser(command.UserID, fmt.Sprintf("<@%s> a déjà un Twin Lunch", user1), 0)
+	// groups are limited to the configured size, so that an extra mention isn't mistaken for a member
+	if len(matches) > groupSize {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "addTooMany", messageData{"Max": groupSize})+"\n"+parsedMentions(command.UserID, matches))
 		return
 	}
 
-	if _, ok := twinLunches[user2]; ok {
-		sendBotMessageToUser(command.UserID, fmt.Sprintf("<@%s> a déjà un Twin Lunch", user2), 0)
+	var users = make([]string, 0, len(matches))
+	for _, match := range matches {
+		if containsUser(users, match[1]) {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "addSameUser", nil))
+			return
+		}
+		users = append(users, match[1])
+	}
+
+	for _, user := range users {
+		if _, ok := twinLunches.Get(user); ok {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "alreadyPaired", messageData{"User": user}))
+			return
+		}
+	}
+
+	for i, user1 := range users {
+		for _, user2 := range users[i+1:] {
+			if exclusion, err := getExclusion(user1, user2); err != nil {
+				commandLogger(command).Println(err)
+				sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+				return
+			} else if exclusion != nil {
+				sendBotMessageToUser(command.UserID, messageTo(command.UserID, "excludedPair", messageData{"User1": user1, "User2": user2, "Reason": exclusion.Reason}))
+				return
+			}
+		}
+	}
+
+	if user, err := inactiveUser(users); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userInfoError", nil))
+		return
+	} else if user != "" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userInactive", messageData{"User": user}))
 		return
 	}
 
-	if _, err := datastoreClient.Put(
-		context.TODO(),
-		datastore.IncompleteKey("TwinLunch", twinLunchListKey),
-		&TwinLunch{user1, user2},
-	); err != nil {
-		logger.Printf("error writing key in datastore: %s", err)
+	var warnings, err = pairingWarnings(command.UserID, users...)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
 		return
 	}
 
-	twinLunches[user1], twinLunches[user2] = user2, user1
+	if !dryRun {
+		if _, err := createTwinLunch(users, 0, command.UserID); err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		}
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "added", messageData{"User1": users[0], "User2": users[1], "Others": users[2:], "Round": currentRound, "Warnings": warnings, "DryRun": dryRun}))
+}
+
+// parsedMentions tells which users were found in a command, so that an admin can see why it was rejected.
+func parsedMentions(user string, matches [][]string) string {
+	var users = make([]string, 0, len(matches))
+	for _, match := range matches {
+		users = append(users, match[1])
+	}
+	return messageTo(user, "parsedMentions", messageData{"Users": users})
+}
+
+// pairingWarnings lists the reasons why pairing the users is discouraged, without forbidding it, in the language of admin.
+func pairingWarnings(admin string, users ...string) ([]string, error) {
+	var warnings []string
+	for _, user := range users {
+		if until := getTwinLunchUser(user).CooldownUntil; time.Now().Before(until) {
+			warnings = append(warnings, messageTo(admin, "cooldownWarning", messageData{"User": user, "Until": until.Format(cooldownLayout)}))
+		}
+	}
+	if maxTwinLunchesPerUser > 0 {
+		for _, user := range users {
+			var count, err = countProgramTwinLunches(user)
+			if err != nil {
+				return nil, err
+			}
+			if isCapped(count) {
+				warnings = append(warnings, messageTo(admin, "cappedWarning", messageData{"User": user, "Count": count, "Max": maxTwinLunchesPerUser}))
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// createTwinLunch creates a pairing of two users or more, slot is its table number or zero.
+func createTwinLunch(users []string, slot int, admin string) (*TwinLunch, error) {
+	var twinLunch = newTwinLunch(users, slot, admin)
+
+	var keys, err = allocateKeys("TwinLunch", twinLunchListKey, 1)
+	if err != nil {
+		return nil, err
+	}
+	twinLunch.Key = keys[0]
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		if _, err := datastoreClient.Put(ctx, twinLunch.Key, twinLunch); err != nil {
+			return fmt.Errorf("error writing key in datastore: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	onTwinLunchCreated(twinLunch, admin)
+
+	return twinLunch, nil
+}
+
+func newTwinLunch(users []string, slot int, admin string) *TwinLunch {
+	var twinLunch = &TwinLunch{User1: users[0], User2: users[1], Emoji: pickPairPersonaEmoji(), CreatedAt: time.Now(), Round: currentRound, Slot: slot, CreatedBy: admin}
+	if len(users) > 2 {
+		twinLunch.Others = append([]string(nil), users[2:]...)
+	}
+	twinLunch.assignAliases()
+	return twinLunch
+}
+
+// onTwinLunchCreated registers a twin lunch once it is saved in datastore, and sends the intros.
+func onTwinLunchCreated(twinLunch *TwinLunch, admin string) {
+	var users = twinLunch.Members()
+
+	twinLunches.Pair(twinLunch)
+	updateTopic()
+	for _, user := range users {
+		delete(unpairedReplies, user)
+	}
+
+	recordAudit(auditActionPairAdded, admin, users...)
+	recordHistory(twinLunch)
 
-	sendBotMessageToUser(command.UserID, fmt.Sprintf("J'ai mis en relation <@%s> et <@%s> pour leur Twin Lunch", user1, user2), 0)
+	publishWorkflowEvent(newWorkflowEvent(workflowEventTwinLunchAdded, twinLunch, admin))
 
-	sendBotMessageToUser(user1, "Salut ! Ton Twin Lunch a été choisi, tu peux discuter avec lui ou elle dans cette conversation sans révéler ton identité :sunglasses:", 2*time.Second)
+	if workflowWebhookURL == "" || !workflowWebhookOnly {
+		for _, user := range users {
+			sendIntroToUser(twinLunch, user)
+		}
+	}
 
-	sendBotMessageToUser(user2, "Salut ! Ton Twin Lunch a été choisi, tu peux discuter avec lui ou elle dans cette conversation sans révéler ton identité :sunglasses:", 3*time.Second)
+	updateHome(users...)
 }
 
 func handleRemoveCommand(command slack.SlashCommand) {
 	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
 
 	if len(matches) != 2 {
-		sendBotMessageToUser(command.UserID, "Tu dois donner deux personnes pour supprimer un Twin Lunch", 0)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "removeUsage", nil)+"\n"+parsedMentions(command.UserID, matches))
 		return
 	}
 
 	var user1, user2 = matches[0][1], matches[1][1]
 
-	if twinLunches[user1] != user2 {
-		sendBotMessageToUser(command.UserID, fmt.Sprintf("<@%s> et <@%s> ne sont pas en Twin Lunch ensemble", user1, user2), 0)
+	var removed, ok = twinLunches.Get(user1)
+	if !ok || user1 == user2 || removed.memberIndex(user2) == -1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notPaired", messageData{"User1": user1, "User2": user2}))
 		return
 	}
 
-	var ctx = context.TODO()
+	if err := deleteTwinLunch(removed); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
 
-	if _, err := datastoreClient.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
-		var it = datastoreClient.Run(ctx, datastore.NewQuery("TwinLunch").Ancestor(twinLunchListKey).Transaction(tx))
-		var key *datastore.Key
-		var twinLunch TwinLunch
+	twinLunches.Unpair(removed)
+	updateTopic()
 
-		for {
-			var k, err = it.Next(&twinLunch)
-			if err == iterator.Done {
-				break
-			} else if err != nil {
-				return fmt.Errorf("error listing keys in datastore: %w", err)
+	recordAudit(auditActionPairRemoved, command.UserID, removed.Members()...)
+
+	onTwinLunchEnded(removed, command.UserID, endRemoved)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "removed", messageData{"User1": user1, "User2": user2}))
+}
+
+// deleteTwinLunch deletes a pairing from datastore, it must still be unpaired afterwards.
+func deleteTwinLunch(removed *TwinLunch) error {
+	return withDatastore(context.Background(), func(ctx context.Context) error {
+		return runInTransaction(ctx, func(tx datastoreTransaction) error {
+			var it = tx.Run(ctx, newDatastoreQuery("TwinLunch").Ancestor(twinLunchListKey))
+			var key *datastore.Key
+			var twinLunch TwinLunch
+
+			for {
+				var k, err = it.Next(&twinLunch)
+				if err == iterator.Done {
+					break
+				} else if err != nil {
+					return fmt.Errorf("error listing keys in datastore: %w", err)
+				}
+				if twinLunch.memberIndex(removed.User1) != -1 {
+					key = k
+					break
+				}
 			}
-			if twinLunch.User1 == user1 || twinLunch.User2 == user1 {
-				key = k
-				break
+
+			if key == nil {
+				return errors.New("could not find twin lunch in datastore")
 			}
-		}
 
-		if key == nil {
-			return errors.New("could not find twin lunch in datastore")
-		}
+			if err := tx.Delete(key); err != nil {
+				return fmt.Errorf("error deleting key in datastore: %w", err)
+			}
 
-		if err := tx.Delete(key); err != nil {
-			return fmt.Errorf("error deleting key in datastore: %w", err)
-		}
+			return nil
+		})
+	})
+}
 
-		return nil
-	}); err != nil {
-		logger.Println(err)
+func handleClearCommand(command slack.SlashCommand) {
+	var cleared, err = clearTwinLunches(command.UserID)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
 		return
 	}
 
-	delete(twinLunches, user1)
-	delete(twinLunches, user2)
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "cleared", messageData{"Count": len(cleared)}))
+}
+
+// clearTwinLunches removes all the pairings and returns them.
+func clearTwinLunches(admin string) ([]*TwinLunch, error) {
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return runInTransaction(ctx, func(tx datastoreTransaction) error {
+			var it = tx.Run(ctx, newDatastoreQuery("TwinLunch").Ancestor(twinLunchListKey))
+			var keys []*datastore.Key
+
+			for {
+				var k, err = it.Next(nil)
+				if err == iterator.Done {
+					break
+				} else if err != nil {
+					return fmt.Errorf("error listing keys in datastore: %w", err)
+				}
+				keys = append(keys, k)
+			}
+
+			if err := tx.DeleteMulti(keys); err != nil {
+				return fmt.Errorf("error deleting keys in datastore: %w", err)
+			}
 
-	sendBotMessageToUser(command.UserID, fmt.Sprintf("J'ai supprimé le Twin Lunch entre <@%s> et <@%s>", user1, user2), 0)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	var cleared = twinLunches.Clear()
+	clearTopic()
+
+	var users = make([]string, 0, 2*len(cleared))
+	for _, twinLunch := range cleared {
+		users = append(users, twinLunch.Members()...)
+		onTwinLunchEnded(twinLunch, admin, endCleared)
+	}
+
+	recordAudit(auditActionPairsCleared, admin, users...)
+
+	return cleared, nil
 }
 
-func handleListCommand(command slack.SlashCommand) {
-	if len(twinLunches) == 0 {
-		sendBotMessageToUser(command.UserID, "Il n'y a aucun Twin Lunch", 0)
-		return
+// endReason tells why a pairing ended.
+type endReason int
+
+const (
+	endRemoved endReason = iota
+	endQuit
+	// endCleared is the end of all the pairings at once, with /twinlunch-clear or /twinlunch-reveal
+	endCleared
+)
+
+// onTwinLunchEnded runs the side effects of a pairing end, once it has been deleted.
+// by is the admin who ended the pairing, or the member who quit it.
+// Only a removal or a quit starts the re-pairing cooldown, clearing the round doesn't.
+func onTwinLunchEnded(twinLunch *TwinLunch, by string, reason endReason) {
+	var members = twinLunch.Members()
+
+	// a member who quits isn't an admin, and they only get the quit confirmation
+	var admin, notified = by, members
+	if reason == endQuit {
+		admin, notified = "", twinLunch.Partners(by)
 	}
 
-	var list = make([]string, 0, len(twinLunches)/2)
-	var listed = make(map[string]struct{}, len(twinLunches))
-	for user1, user2 := range twinLunches {
-		if _, ok := listed[user1]; ok {
-			continue
+	onTwinLunchClosed(twinLunch, admin)
+
+	if reason == endRemoved || reason == endQuit {
+		for _, user := range members {
+			startCooldown(user)
 		}
-		list = append(list, fmt.Sprintf("• <@%s> et <@%s>", user1, user2))
-		listed[user1], listed[user2] = struct{}{}, struct{}{}
 	}
 
-	sendBotMessageToUser(command.UserID, "Voilà la liste des Twin Lunch :\n\n"+strings.Join(list, "\n"), 0)
+	cleanupRelayedMessages(twinLunch, cleanupOnRemove)
+	sendTwinLunchEnded(twinLunch, notified)
+
+	updateHome(members...)
 }
 
-func handleClearCommand(command slack.SlashCommand) {
-	var ctx = context.TODO()
-
-	if _, err := datastoreClient.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
-		var it = datastoreClient.Run(ctx, datastore.NewQuery("TwinLunch").Ancestor(twinLunchListKey).Transaction(tx))
-		var keys []*datastore.Key
-
-		for {
-			var k, err = it.Next(nil)
-			if err == iterator.Done {
-				break
-			} else if err != nil {
-				return fmt.Errorf("error listing keys in datastore: %w", err)
-			}
-			keys = append(keys, k)
+// onTwinLunchClosed runs the side effects of a pairing end which don't concern the members,
+// swaps run them alone as the members get a new pairing right away.
+func onTwinLunchClosed(twinLunch *TwinLunch, admin string) {
+	var members = twinLunch.Members()
+
+	dropDeliveries(members...)
+	recordHistoryMessages(twinLunch)
+
+	if pinIntro {
+		for _, user := range members {
+			go unpinIntro(user)
 		}
+	}
+
+	publishWorkflowEvent(newWorkflowEvent(workflowEventTwinLunchRemoved, twinLunch, admin))
+}
 
-		if err := tx.DeleteMulti(keys); err != nil {
-			return fmt.Errorf("error deleting keys in datastore: %w", err)
+// forwardTwinLunchMessage relays a message to user, the text first and then the attached files.
+func forwardTwinLunchMessage(twinLunch *TwinLunch, user string, message *slackevents.MessageEvent) {
+	var relayLogger = logger.With("relay", "message", "from", logUser(message.User), "to", logUser(user))
+
+	var channel, err = getChannelForUser(user)
+	if err != nil {
+		relayLogger.Println(err)
+		if isUnreachableUserError(err) {
+			notifyUnreachablePartner(message.User, user)
 		}
+		return
+	}
 
-		return nil
-	}); err != nil {
+	var codename = twinLunch.Codename(message.User)
+	var emoji = personaEmoji(twinLunch, message.User)
+
+	if message.Text != "" {
+		var relayed = wrapRelayedText(neutralizeMentions(user, message.Text))
+		var text = addRelayDisclaimer(twinLunch, user, relayed)
+		var options = []slack.MsgOption{
+			slack.MsgOptionText(tagOrigin("RELAY", channel, user, text), false),
+			slack.MsgOptionIconEmoji(emoji),
+			slack.MsgOptionUsername(codename),
+			// links stay clickable, they just aren't previewed
+			slack.MsgOptionDisableLinkUnfurl(),
+			slack.MsgOptionDisableMediaUnfurl(),
+		}
+
+		enqueueDelivery(channel, delivery{user: user, logger: relayLogger, send: func() error {
+			// the intro is sent first in the same queue, but it may have failed meanwhile
+			if getTwinLunchUser(user).PendingIntro {
+				relayLogger.Println("not relaying message, the intro of the recipient failed")
+				return nil
+			}
+
+			var _, ts, err = slackClient.PostMessage(channel, options...)
+			if err != nil {
+				if isUnreachableUserError(err) {
+					notifyUnreachablePartner(message.User, user)
+				}
+				return fmt.Errorf("error sending message: %w", err)
+			}
+			if twinLunch.Key != nil {
+				// in groups the original message has several copies, reactions on it can't be mirrored
+				recordRelayedMessage(twinLunch.Key.ID, message.Channel, message.TimeStamp, channel, ts, len(twinLunch.Others) == 0)
+				recordRelayedCopy(twinLunch.Key.ID, message.Channel, message.TimeStamp, channel, ts, text != relayed)
+			}
+			return nil
+		}})
+	}
+
+	for _, file := range message.Files {
+		var file = file
+		enqueueDelivery(channel, delivery{user: user, logger: relayLogger.With("file", file.ID), send: func() error {
+			if getTwinLunchUser(user).PendingIntro {
+				relayLogger.Println("not relaying file, the intro of the recipient failed")
+				return nil
+			}
+
+			var err = forwardFile(channel, user, codename, file)
+			if isUnreachableUserError(err) {
+				notifyUnreachablePartner(message.User, user)
+			}
+			return err
+		}})
+	}
+}
+
+func sendBotMessageToUser(user string, text string) {
+	var channel, err = getChannelForUser(user)
+	if err != nil {
 		logger.Println(err)
 		return
 	}
 
-	twinLunches = make(map[string]string)
+	enqueueBotMessage(channel, user, text)
+}
 
-	sendBotMessageToUser(command.UserID, "J'ai supprimé tous les Twin Lunch :fire:", 0)
+func sendBotMessageToChannel(channel string, text string) {
+	enqueueBotMessage(channel, "", text)
 }
 
-func forwardTwinLunchMessage(user string, text string) {
-	var channel, err = getChannelForUser(user)
+func enqueueBotMessage(channel string, user string, text string) {
+	enqueueDelivery(channel, delivery{user: user, send: func() error {
+		var _, err = postBotMessage(channel, text)
+		return err
+	}})
+}
+
+func postBotMessage(channel string, text string) (string, error) {
+	var _, ts, err = slackClient.PostMessage(
+		channel,
+		slack.MsgOptionIconEmoji("robot_face"),
+		slack.MsgOptionUsername("Twin Lunch Bot"),
+		slack.MsgOptionText(tagOrigin("BOT", channel, "", "_bip bip_ "+text), false),
+	)
 	if err != nil {
-		log.Println(err)
+		return "", fmt.Errorf("error sending message: %w", err)
+	}
+	return ts, nil
+}
+
+func tagOrigin(origin string, channel string, user string, text string) string {
+	if !originTags {
+		return text
+	}
+	if user == "" {
+		return fmt.Sprintf("`[%s] channel=%s` %s", origin, channel, text)
+	}
+	return fmt.Sprintf("`[%s] channel=%s user=%s` %s", origin, channel, user, text)
+}
+
+func introMessage(twinLunch *TwinLunch, user string) string {
+	return messageTo(user, "intro", messageData{"Round": twinLunch.Round, "Slot": twinLunch.Slot, "Partners": 1 + len(twinLunch.Others)})
+}
+
+// sendIntroToUser sends the intro in the background, if it fails it is marked pending and retried later.
+func sendIntroToUser(twinLunch *TwinLunch, user string) {
+	if !startIntro(user) {
 		return
 	}
 
-	time.AfterFunc(time.Second, func() {
-		if _, _, err := slackClient.PostMessage(
-			channel,
-			slack.MsgOptionText(text, false),
-			slack.MsgOptionIconEmoji("question"),
-			slack.MsgOptionUsername("Ton Twin Lunch"),
-		); err != nil {
-			log.Printf("error sending message: %w", err)
+	var channel, err = getChannelForUser(user)
+	if err != nil {
+		logger.Println(err)
+		setPendingIntro(user, true)
+		if isUnreachableUserError(err) {
+			notifyIntroFailed(twinLunch, user)
 		}
+		endIntro(user)
+		return
+	}
+
+	var text = introMessage(twinLunch, user)
+
+	enqueueDelivery(channel, delivery{
+		user: user,
+		send: func() error {
+			defer endIntro(user)
+
+			if err := postIntro(channel, text); err != nil {
+				setPendingIntro(user, true)
+				if isUnreachableUserError(err) {
+					notifyIntroFailed(twinLunch, user)
+				}
+				return err
+			}
+
+			setPendingIntro(user, false)
+			return nil
+		},
+		dropped: func() { endIntro(user) },
 	})
 }
 
-func sendBotMessageToUser(user string, text string, after time.Duration) {
+func postIntro(channel string, text string) error {
+	var ts, err = postBotMessage(channel, text)
+	if err != nil {
+		return err
+	}
+
+	if pinIntro {
+		if err := slackClient.AddPin(channel, slack.NewRefToMessage(channel, ts)); err != nil {
+			logger.Printf("error pinning intro message: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func unpinIntro(user string) {
 	var channel, err = getChannelForUser(user)
 	if err != nil {
 		logger.Println(err)
 		return
 	}
 
-	sendBotMessageToChannel(channel, text, after)
+	items, _, err := slackClient.ListPins(channel)
+	if err != nil {
+		logger.Printf("error listing pinned messages: %s", err)
+		return
+	}
+
+	for _, item := range items {
+		if item.Message == nil || item.Message.BotID != slackBotID {
+			continue
+		}
+		if err := slackClient.RemovePin(channel, slack.NewRefToMessage(channel, item.Message.Timestamp)); err != nil {
+			logger.Printf("error unpinning intro message: %s", err)
+		}
+	}
 }
 
-func sendBotMessageToChannel(channel string, text string, after time.Duration) {
-	if after == 0 {
-		after = time.Second
+func sendPlaceholderToUser(user string) (string, string) {
+	var channel, err = getChannelForUser(user)
+	if err != nil {
+		logger.Println(err)
+		return "", ""
+	}
+
+	ts, err := postBotMessage(channel, messageTo(user, "placeholder", nil))
+	if err != nil {
+		logger.Println(err)
+		return channel, ""
 	}
 
-	time.AfterFunc(after, func() {
-		if _, _, err := slackClient.PostMessage(
-			channel,
-			slack.MsgOptionIconEmoji("robot_face"),
-			slack.MsgOptionUsername("Twin Lunch Bot"),
-			slack.MsgOptionText("_bip bip_ "+text, false),
-		); err != nil {
-			logger.Printf("error sending message: %w", err)
+	return channel, ts
+}
+
+func replacePlaceholder(user string, channel string, ts string, text string) {
+	if ts != "" {
+		var _, _, _, err = slackClient.UpdateMessage(channel, ts, slack.MsgOptionText(tagOrigin("BOT", channel, "", "_bip bip_ "+text), false))
+		if err == nil {
+			return
 		}
-	})
+		logger.Printf("error updating message: %s", err)
+	}
+
+	sendBotMessageToUser(user, text)
+}
+
+func uploadFileToUser(user string, filename string, title string, content io.Reader) error {
+	var channel, err = getChannelForUser(user)
+	if err != nil {
+		return err
+	}
+
+	if _, err := slackClient.UploadFile(slack.FileUploadParameters{
+		Reader:   content,
+		Filename: filename,
+		Title:    title,
+		Channels: []string{channel},
+	}); err != nil {
+		return fmt.Errorf("error uploading file: %w", err)
+	}
+
+	return nil
 }
 
 func getChannelForUser(user string) (string, error) {
@@ -385,14 +1311,6 @@ func getChannelForUser(user string) (string, error) {
 	return channel.ID, nil
 }
 
-func runSlackClient() {
-	logger.Println("running slack client...")
-
-	if err := slackClient.Run(); err != nil {
-		logger.Fatal(err)
-	}
-}
-
 func getSecrets(ctx context.Context, names ...string) (map[string]string, error) {
 	client, err := secretmanager.NewClient(ctx)
 	if err != nil {
@@ -416,22 +1334,38 @@ func getSecrets(ctx context.Context, names ...string) (map[string]string, error)
 	return secrets, nil
 }
 
-func loadTwinLunches(ctx context.Context) {
+// loadTwinLunches reads the pairings from datastore, transient errors are retried by withDatastore.
+// A user in several pairings is logged, and only the last one read is kept for this user.
+func loadTwinLunches(ctx context.Context) error {
 	logger.Println("loading twin lunches...")
 
 	var result []*TwinLunch
 
-	if _, err := datastoreClient.GetAll(
-		ctx,
-		datastore.NewQuery("TwinLunch").Ancestor(twinLunchListKey),
-		&result,
-	); err != nil {
-		logger.Fatalf("error reading twin lunches from datastore %s", err)
+	if err := withDatastore(ctx, func(ctx context.Context) error {
+		result = nil
+		var _, err = datastoreClient.GetAll(
+			ctx,
+			newDatastoreQuery("TwinLunch").Ancestor(twinLunchListKey),
+			&result,
+		)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error reading twin lunches from datastore: %w", err)
 	}
 
 	for _, twinLunch := range result {
-		twinLunches[twinLunch.User1], twinLunches[twinLunch.User2] = twinLunch.User2, twinLunch.User1
+		twinLunches.Pair(twinLunch)
+	}
+
+	for _, twinLunch := range result {
+		for _, user := range twinLunch.Members() {
+			if loaded, _ := twinLunches.Get(user); loaded != twinLunch {
+				logger.Printf("warning: user %s is in twin lunches %s and %s", user, twinLunch.Key, loaded.Key)
+			}
+		}
 	}
 
 	logger.Printf("loaded %d twin lunches", len(result))
+
+	return nil
 }
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// storedTwinLunches returns the members of the twin lunches saved in the fake datastore.
+func storedTwinLunches(t *testing.T, fd *fakeDatastore) [][]string {
+	t.Helper()
+
+	var stored []*TwinLunch
+	if _, err := fd.GetAll(context.Background(), newDatastoreQuery("TwinLunch").Ancestor(twinLunchListKey), &stored); err != nil {
+		t.Fatal(err)
+	}
+
+	var members [][]string
+	for _, twinLunch := range stored {
+		members = append(members, twinLunch.Members())
+	}
+	return members
+}
+
+// checkMessages checks that each channel received messages containing the wanted texts, in order.
+func checkMessages(t *testing.T, fs *fakeSlack, want map[string][]string) {
+	t.Helper()
+
+	for channel, wantTexts := range want {
+		var texts = fs.messagesTo(channel)
+		if len(texts) != len(wantTexts) {
+			t.Errorf("messages to %s = %q, want %d messages", channel, texts, len(wantTexts))
+			continue
+		}
+		for i, wantText := range wantTexts {
+			if !strings.Contains(texts[i], wantText) {
+				t.Errorf("message %d to %s = %q, want it to contain %q", i, channel, texts[i], wantText)
+			}
+		}
+	}
+}
+
+func TestHandleAddCommand(t *testing.T) {
+	var intro = introMessage(&TwinLunch{Round: 1}, "U1")
+
+	var tests = []struct {
+		name       string
+		user       string
+		text       string
+		groupSize  int
+		paired     *TwinLunch
+		failures   map[string]error
+		want       map[string][]string
+		wantStored [][]string
+	}{
+		{
+			name: "success",
+			user: "UADMIN",
+			text: "<@U1> <@U2>",
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("added", messageData{"User1": "U1", "User2": "U2", "Others": []string{}, "Round": 1, "Warnings": []string(nil), "DryRun": false})},
+				dmChannel("U1"):     {intro},
+				dmChannel("U2"):     {intro},
+			},
+			wantStored: [][]string{{"U1", "U2"}},
+		},
+		{
+			name: "fewer than two mentions",
+			user: "UADMIN",
+			text: "<@U1>",
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("addUsage", nil) + "\n" + message("parsedMentions", messageData{"Users": []string{"U1"}})},
+				dmChannel("U1"):     nil,
+			},
+		},
+		{
+			name: "more than two mentions",
+			user: "UADMIN",
+			text: "<@U1> <@U2> <@U3>",
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("addTooMany", messageData{"Max": 2}) + "\n" + message("parsedMentions", messageData{"Users": []string{"U1", "U2", "U3"}})},
+				dmChannel("U1"):     nil,
+				dmChannel("U2"):     nil,
+				dmChannel("U3"):     nil,
+			},
+		},
+		{
+			name:      "group within the configured size",
+			user:      "UADMIN",
+			text:      "<@U1> <@U2> <@U3>",
+			groupSize: 3,
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("added", messageData{"User1": "U1", "User2": "U2", "Others": []string{"U3"}, "Round": 1, "Warnings": []string(nil), "DryRun": false})},
+				dmChannel("U3"):     {introMessage(&TwinLunch{Round: 1, Others: []string{"U3"}}, "U3")},
+			},
+			wantStored: [][]string{{"U1", "U2", "U3"}},
+		},
+		{
+			name: "paired with themselves",
+			user: "UADMIN",
+			text: "<@U1> <@U1>",
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("addSameUser", nil)},
+				dmChannel("U1"):     nil,
+			},
+		},
+		{
+			name:   "already paired",
+			user:   "UADMIN",
+			text:   "<@U1> <@U2>",
+			paired: &TwinLunch{User1: "U2", User2: "U3"},
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("alreadyPaired", messageData{"User": "U2"})},
+				dmChannel("U1"):     nil,
+				dmChannel("U2"):     nil,
+			},
+		},
+		{
+			name: "not admin",
+			user: "UOTHER",
+			text: "<@U1> <@U2>",
+			want: map[string][]string{
+				dmChannel("UOTHER"): {message("notAdmin", nil)},
+				dmChannel("U1"):     nil,
+				dmChannel("U2"):     nil,
+			},
+		},
+		{
+			name:     "datastore error",
+			user:     "UADMIN",
+			text:     "<@U1> <@U2>",
+			failures: map[string]error{"AllocateIDs": errors.New("datastore is down")},
+			want: map[string][]string{
+				dmChannel("UADMIN"): {message("datastoreError", nil)},
+				dmChannel("U1"):     nil,
+				dmChannel("U2"):     nil,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var fs, fd = setupFakes(t)
+			twinLunchAdmins["UADMIN"] = struct{}{}
+			if test.groupSize != 0 {
+				var savedGroupSize = groupSize
+				groupSize = test.groupSize
+				t.Cleanup(func() { groupSize = savedGroupSize })
+			}
+			if test.paired != nil {
+				twinLunches.Pair(test.paired)
+			}
+			for method, err := range test.failures {
+				fd.failNext(method, err)
+			}
+
+			handleCommand(slack.SlashCommand{Command: "/twinlunch-add", UserID: test.user, Text: test.text})
+			deliveries.Wait()
+
+			checkMessages(t, fs, test.want)
+
+			if stored := storedTwinLunches(t, fd); !reflect.DeepEqual(stored, test.wantStored) {
+				t.Errorf("stored twin lunches = %q, want %q", stored, test.wantStored)
+			}
+		})
+	}
+}
+
+func TestReplacePlaceholder(t *testing.T) {
+	var tests = []struct {
+		name        string
+		updateErr   error
+		wantPosted  []string
+		wantUpdated []postedMessage
+	}{
+		{
+			name:        "updated",
+			wantPosted:  []string{message("placeholder", nil)},
+			wantUpdated: []postedMessage{{dmChannel("UADMIN"), "1.000000", "_bip bip_ done"}},
+		},
+		{
+			name:       "update failure",
+			updateErr:  errors.New("cant_update_message"),
+			wantPosted: []string{message("placeholder", nil), "done"},
+		},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var fs, _ = setupFakes(t)
+			if test.updateErr != nil {
+				fs.failNext("UpdateMessage", test.updateErr)
+			}
+
+			var channel, ts = sendPlaceholderToUser("UADMIN")
+			if channel != dmChannel("UADMIN") || ts == "" {
+				t.Fatalf("placeholder posted in %q at %q, want a message in %q", channel, ts, dmChannel("UADMIN"))
+			}
+			replacePlaceholder("UADMIN", channel, ts, "done")
+			deliveries.Wait()
+
+			checkMessages(t, fs, map[string][]string{channel: test.wantPosted})
+			if !reflect.DeepEqual(fs.updated, test.wantUpdated) {
+				t.Errorf("updated messages = %q, want %q", fs.updated, test.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestReceiveEventsChecksTeam(t *testing.T) {
+	var savedTeamID, savedAppID = slackTeamID, slackAppID
+	slackTeamID, slackAppID = "T1", "A1"
+	t.Cleanup(func() { slackTeamID, slackAppID = savedTeamID, savedAppID })
+
+	var tests = []struct {
+		name     string
+		command  slack.SlashCommand
+		accepted bool
+	}{
+		{"matching team and app", slack.SlashCommand{Command: "/twinlunch-list", TeamID: "T1", APIAppID: "A1"}, true},
+		{"mismatched team", slack.SlashCommand{Command: "/twinlunch-list", TeamID: "T2", APIAppID: "A1"}, false},
+		{"mismatched app", slack.SlashCommand{Command: "/twinlunch-list", TeamID: "T1", APIAppID: "A2"}, false},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			// once the next event is received, the command is either dispatched or rejected
+			var events = make(chan socketmode.Event)
+			var ctx, cancel = context.WithCancel(context.Background())
+			var commands = make(chan slack.SlashCommand, 1)
+
+			var done = make(chan struct{})
+			go func() {
+				defer close(done)
+				receiveEvents(ctx, events, newFakeAcknowledger(), make(chan *slackevents.MessageEvent), commands, make(chan reactionChange), make(chan string))
+			}()
+
+			events <- socketmode.Event{Type: socketmode.EventTypeSlashCommand, Data: test.command, Request: &socketmode.Request{EnvelopeID: "1"}}
+			events <- socketmode.Event{Type: socketmode.EventTypeConnected}
+			cancel()
+			<-done
+
+			var _, accepted = <-commands
+			if accepted != test.accepted {
+				t.Errorf("command accepted = %t, want %t", accepted, test.accepted)
+			}
+		})
+	}
+}
+
+func TestReceiveEventsAcksBusyLoop(t *testing.T) {
+	var savedTeamID = slackTeamID
+	slackTeamID = "T1"
+	t.Cleanup(func() { slackTeamID = savedTeamID })
+
+	var events = make(chan socketmode.Event)
+	var acknowledger = newFakeAcknowledger()
+	var ctx, cancel = context.WithCancel(context.Background())
+	// nobody reads the commands, as if the run loop were busy
+	var commands = make(chan slack.SlashCommand)
+
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		receiveEvents(ctx, events, acknowledger, make(chan *slackevents.MessageEvent), commands, make(chan reactionChange), make(chan string))
+	}()
+
+	var command = slack.SlashCommand{Command: "/twinlunch-list", TeamID: "T1", UserID: "UADMIN"}
+	events <- socketmode.Event{Type: socketmode.EventTypeSlashCommand, Data: command, Request: &socketmode.Request{EnvelopeID: "1"}}
+
+	select {
+	case response := <-acknowledger.acks:
+		var payload, _ = response.Payload.(map[string]interface{})
+		if response.EnvelopeID != "1" || payload["text"] != messageTo("UADMIN", "commandReceived", nil) {
+			t.Errorf("acknowledgement = %+v, want the command received message", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command not acknowledged while the run loop is busy")
+	}
+
+	if received := <-commands; received.Command != command.Command {
+		t.Errorf("command = %q, want %q", received.Command, command.Command)
+	}
+	cancel()
+	<-done
+}
+
+func TestPinIntro(t *testing.T) {
+	var fs, _ = setupFakes(t)
+
+	var savedPinIntro, savedBotID = pinIntro, slackBotID
+	pinIntro, slackBotID = true, "B1"
+	t.Cleanup(func() { pinIntro, slackBotID = savedPinIntro, savedBotID })
+
+	if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Wait()
+
+	// the intros are sent from one queue per channel, in any order
+	var want = make(map[slack.ItemRef]bool)
+	for _, posted := range fs.posted {
+		want[slack.NewRefToMessage(posted.Channel, posted.TS)] = true
+	}
+	var pins = make(map[slack.ItemRef]bool)
+	for _, pin := range fs.pins {
+		pins[pin] = true
+	}
+	if len(want) != 2 || !reflect.DeepEqual(pins, want) {
+		t.Errorf("pins = %+v, want the intros %+v", fs.pins, want)
+	}
+
+	unpinIntro("U1")
+	if len(fs.pins) != 1 || fs.pins[0].Channel != dmChannel("U2") {
+		t.Errorf("pins after unpinning U1 = %+v, want only the intro of U2", fs.pins)
+	}
+}
+
+func TestUserRegexp(t *testing.T) {
+	var tests = []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"piped", "<@U1|alice>", []string{"U1"}},
+		{"unpiped", "<@U1>", []string{"U1"}},
+		{"empty name", "<@U1|>", []string{"U1"}},
+		{"enterprise user", "<@W1|alice>", []string{"W1"}},
+		{"mixed", "<@U1|alice> and <@U2> then <@U3|carol>", []string{"U1", "U2", "U3"}},
+		{"adjacent", "<@U1><@U2|bob>", []string{"U1", "U2"}},
+		{"plain text", "@alice", nil},
+		{"channel", "<#C1|general>", nil},
+		{"broadcast", "<!here>", nil},
+	}
+
+	for _, test := range tests {
+		var users []string
+		for _, match := range userRegexp.FindAllStringSubmatch(test.text, -1) {
+			users = append(users, match[1])
+		}
+		if !reflect.DeepEqual(users, test.want) {
+			t.Errorf("%s: users of %q = %q, want %q", test.name, test.text, users, test.want)
+		}
+	}
+}
+
+func TestParsedMentions(t *testing.T) {
+	var tests = []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "alice bob", []string{}},
+		{"piped", "<@U1|alice>", []string{"U1"}},
+		{"unpiped", "<@U1>", []string{"U1"}},
+		{"mixed", "<@U1|alice> <@U2>", []string{"U1", "U2"}},
+	}
+
+	for _, test := range tests {
+		var text = parsedMentions("UADMIN", userRegexp.FindAllStringSubmatch(test.text, -1))
+		if want := message("parsedMentions", messageData{"Users": test.want}); text != want {
+			t.Errorf("%s: parsedMentions(%q) = %q, want %q", test.name, test.text, text, want)
+		}
+	}
+
+	if text := message("parsedMentions", messageData{"Users": []string{"U1", "U2"}}); !strings.Contains(text, "<@U1>, <@U2>") {
+		t.Errorf("parsed mentions = %q, want the users listed", text)
+	}
+}
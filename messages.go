@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// messageData holds the fields interpolated in a message template.
+type messageData map[string]interface{}
+
+// pairData lists the users of a pairing, Others are the members of groups besides User1 and User2.
+type pairData struct {
+	User1, User2 string
+	Others       []string
+}
+
+func newPairData(users []string) pairData {
+	return pairData{users[0], users[1], users[2:]}
+}
+
+type skippedData struct {
+	User, Reason string
+}
+
+var errUnknownMessage = errors.New("unknown message template")
+
+const defaultMessageLanguage = "fr"
+
+var messageCatalogs = map[string]map[string]string{
+	"en": enMessages,
+	"fr": frMessages,
+}
+
+var (
+	messageLanguage  = defaultMessageLanguage
+	defaultMessages  = frMessages
+	defaultTemplates = parseMessages(frMessages)
+	messageTemplates = defaultTemplates
+
+	// languageTemplates holds the templates of every catalog, for the users who prefer another language
+	languageTemplates = parseCatalogs()
+
+	// slackLocales holds the languages of the users' Slack accounts, learnt when they are paired
+	slackLocalesMu sync.Mutex
+	slackLocales   = make(map[string]string)
+)
+
+func parseMessage(id string, text string) (*template.Template, error) {
+	return template.New(id).Option("missingkey=error").Parse(text)
+}
+
+func parseMessages(catalog map[string]string) map[string]*template.Template {
+	var templates = make(map[string]*template.Template, len(catalog))
+	for id, text := range catalog {
+		templates[id] = template.Must(parseMessage(id, text))
+	}
+	return templates
+}
+
+func parseCatalogs() map[string]map[string]*template.Template {
+	var templates = make(map[string]map[string]*template.Template, len(messageCatalogs))
+	for lang, catalog := range messageCatalogs {
+		templates[lang] = parseMessages(catalog)
+	}
+	return templates
+}
+
+// languageOf returns the language of a locale such as "en", "en-US" or "en_US.UTF-8".
+func languageOf(locale string) string {
+	var lang = strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "_.-"); i != -1 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// setMessageLanguage selects the catalog of lang, such as "en" or "en_US.UTF-8".
+// It must be called before loadMessageTemplates.
+func setMessageLanguage(lang string) {
+	lang = languageOf(lang)
+
+	var catalog, ok = messageCatalogs[lang]
+	if !ok {
+		logger.Printf("warning: no messages for language %q, using %q", lang, defaultMessageLanguage)
+		lang, catalog = defaultMessageLanguage, messageCatalogs[defaultMessageLanguage]
+	}
+
+	messageLanguage = lang
+	defaultMessages = catalog
+	defaultTemplates = languageTemplates[lang]
+	messageTemplates = defaultTemplates
+
+	logger.Printf("using %q messages", lang)
+}
+
+// loadMessageTemplates overrides the default messages with the templates of a JSON file (message ID → template).
+// Invalid templates are ignored and the defaults are kept.
+func loadMessageTemplates(path string) {
+	var content, err = os.ReadFile(path)
+	if err != nil {
+		logger.Printf("error reading message templates, using defaults: %s", err)
+		return
+	}
+
+	var overrides map[string]string
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		logger.Printf("error decoding message templates, using defaults: %s", err)
+		return
+	}
+
+	var templates = make(map[string]*template.Template, len(defaultTemplates))
+	for id, tmpl := range defaultTemplates {
+		templates[id] = tmpl
+	}
+
+	for id, text := range overrides {
+		if _, ok := defaultMessages[id]; !ok {
+			logger.Printf("ignoring unknown message template %q", id)
+			continue
+		}
+
+		var tmpl, err = parseMessage(id, text)
+		if err != nil {
+			logger.Printf("ignoring invalid message template %q: %s", id, err)
+			continue
+		}
+
+		templates[id] = tmpl
+	}
+
+	messageTemplates = templates
+
+	logger.Printf("loaded %d message templates", len(overrides))
+}
+
+// message renders the message template id, falling back on the default template if rendering fails.
+func message(id string, data messageData) string {
+	var text, err = renderMessage(messageTemplates[id], data)
+	if err != nil {
+		logger.Printf("error rendering message template %q, using default: %s", id, err)
+		if text, err = renderMessage(defaultTemplates[id], data); err != nil {
+			logger.Printf("error rendering default message template %q: %s", id, err)
+		}
+	}
+	return text
+}
+
+// messageTo renders the message template id in the language of user, the custom templates only apply to the global
+// language. Users without a known language get the global one.
+func messageTo(user string, id string, data messageData) string {
+	var lang = userLanguage(user)
+	if lang == "" || lang == messageLanguage {
+		return message(id, data)
+	}
+
+	var text, err = renderMessage(languageTemplates[lang][id], data)
+	if err != nil {
+		logger.Printf("error rendering %q message template %q, using global language: %s", lang, id, err)
+		return message(id, data)
+	}
+	return text
+}
+
+// userLanguage returns the language chosen by user with /twinlunch-prefs, or the one of their Slack account.
+func userLanguage(user string) string {
+	if lang := getTwinLunchUser(user).Language; lang != "" {
+		return lang
+	}
+
+	slackLocalesMu.Lock()
+	defer slackLocalesMu.Unlock()
+
+	return slackLocales[user]
+}
+
+// rememberSlackLocale keeps the language of a Slack account if there are messages for it.
+func rememberSlackLocale(user string, locale string) {
+	var lang = languageOf(locale)
+	if _, ok := messageCatalogs[lang]; !ok {
+		return
+	}
+
+	slackLocalesMu.Lock()
+	defer slackLocalesMu.Unlock()
+
+	slackLocales[user] = lang
+}
+
+func renderMessage(tmpl *template.Template, data messageData) (string, error) {
+	if tmpl == nil {
+		return "", errUnknownMessage
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,173 @@
+package main
+
+var enMessages = map[string]string{
+	"activityDone":             "Here is the activity from {{.From}} to {{.To}} ({{.Count}} entries)",
+	"activityEmpty":            "There is no activity in this period",
+	"activityError":            "I couldn't prepare the activity :warning:",
+	"activityFileTitle":        "Twin Lunch activity from {{.From}} to {{.To}}",
+	"activityInvalidDate":      "Dates must use the YYYY-MM-DD format",
+	"activityInvalidRange":     "The end date must be after the start date",
+	"activityUsage":            "You must give a start date and an end date (YYYY-MM-DD)",
+	"addSameUser":              "You must give different people to create a Twin Lunch",
+	"addTooMany":               "You can give at most {{.Max}} people to create a Twin Lunch",
+	"addUsage":                 "You must give at least two people to create a Twin Lunch, add `--dry-run` to preview it",
+	"added":                    "I {{if .DryRun}}would pair{{else}}paired{{end}} <@{{.User1}}> and <@{{.User2}}>{{range .Others}} and <@{{.}}>{{end}} for their Twin Lunch{{range .Warnings}}\n{{.}}{{end}}{{if .DryRun}}\n_Dry run: nothing was saved and nobody was notified_{{end}}",
+	"adminAddUsage":            "Use `/twinlunch-admin-add @someone`",
+	"adminAdded":               "<@{{.User}}> can now manage Twin Lunch",
+	"adminRemoveUsage":         "Use `/twinlunch-admin-remove @someone`",
+	"adminRemoved":             "<@{{.User}}> can't manage Twin Lunch anymore",
+	"aliasFox":                 "Anonymous Fox",
+	"aliasFrog":                "Anonymous Frog",
+	"aliasHedgehog":            "Anonymous Hedgehog",
+	"aliasKoala":               "Anonymous Koala",
+	"aliasOctopus":             "Anonymous Octopus",
+	"aliasOtter":               "Anonymous Otter",
+	"aliasOwl":                 "Anonymous Owl",
+	"aliasPanda":               "Anonymous Panda",
+	"aliasPenguin":             "Anonymous Penguin",
+	"aliasTiger":               "Anonymous Tiger",
+	"aliasTurtle":              "Anonymous Turtle",
+	"aliasWolf":                "Anonymous Wolf",
+	"alreadyAdmin":             "<@{{.User}}> already manages Twin Lunch",
+	"alreadyPaired":            "<@{{.User}}> already has a Twin Lunch",
+	"autoPairReport":           "{{if .Created}}I {{if .DryRun}}would create{{else}}created{{end}} {{len .Created}} Twin Lunch:\n\n{{range .Created}}• <@{{.User1}}> and <@{{.User2}}>{{range .Others}} and <@{{.}}>{{end}}\n{{end}}{{else}}I {{if .DryRun}}wouldn't{{else}}didn't{{end}} create any Twin Lunch\n{{end}}{{if .Left}}\nNo one could be found for {{range $i, $user := .Left}}{{if $i}}, {{end}}<@{{$user}}>{{end}}\n{{end}}{{if .Skipped}}\nThese people were left out:\n{{range .Skipped}}• <@{{.User}}> {{.Reason}}\n{{end}}{{end}}{{if .Repeats}}\nFor lack of a better option, these people already had a Twin Lunch together in the last {{.RepeatRounds}} rounds:\n{{range .Repeats}}• <@{{.User1}}> and <@{{.User2}}>{{range .Others}} and <@{{.}}>{{end}}\n{{end}}{{end}}{{if .OperationID}}\n:warning: {{.Pending}} Twin Lunch couldn't be created, use `/twinlunch-resume-pair {{.OperationID}}` to try again\n{{end}}{{if .DryRun}}\n_Dry run: nothing was saved and nobody was notified_{{end}}",
+	"broadcast":                ":mega: {{.Text}}",
+	"broadcastNobody":          "Nobody has a Twin Lunch right now, I didn't send anything",
+	"broadcastSent":            "I'm sending your announcement to {{.Count}} people",
+	"broadcastUsage":           "Use `/twinlunch-broadcast message`",
+	"cappedWarning":            ":warning: <@{{.User}}> already had {{.Count}} Twin Lunch in this program (maximum {{.Max}})",
+	"cleared":                  "I removed all the Twin Lunch :fire:",
+	"codenameInvalidChars":     "The codename can't contain the <, > or @ characters",
+	"codenameLength":           "The codename must be between {{.Min}} and {{.Max}} characters long",
+	"codenameReserved":         "The codename “{{.Codename}}” is reserved",
+	"codenameSet":              "Your codename is now “{{.Codename}}”",
+	"codenameSetFor":           "The codename of <@{{.User}}> is now “{{.Codename}}”",
+	"codenameTaken":            "The codename “{{.Codename}}” is already used in this Twin Lunch",
+	"commandReceived":          "Got it, I'm handling your request and I'll answer in a direct message :robot_face:",
+	"configuredAdmin":          "<@{{.User}}> is in `TWIN_LUNCH_ADMINS`, the configuration must be changed to remove their rights",
+	"cooldownWarning":          ":warning: <@{{.User}}> is on a break until {{.Until}}",
+	"datastoreError":           "I couldn't reach the database, please try again later :warning:",
+	"defaultCodename":          "Your Twin Lunch",
+	"ended":                    "{{if .Round}}Your Twin Lunch of round {{.Round}} has ended{{else}}Your Twin Lunch has ended{{end}}, your messages won't be forwarded anymore :wave:",
+	"excludeSameUser":          "You must give two different people to create an exclusion",
+	"excludeUsage":             "You must give two people to create an exclusion",
+	"excluded":                 "<@{{.User1}}> and <@{{.User2}}> won't be paired anymore",
+	"excludedPair":             "<@{{.User1}}> and <@{{.User2}}> can't be paired{{if .Reason}}: {{.Reason}}{{end}}",
+	"exclusions":               "Here are the exclusions (page {{.Page}}/{{.Pages}}):\n\n{{range .Exclusions}}• <@{{.User1}}> and <@{{.User2}}>, by <@{{.CreatedBy}}> on {{.CreatedAt.Format \"2006-01-02\"}}{{if .Reason}}: {{.Reason}}{{end}}\n{{end}}",
+	"exclusionsInvalidPage":    "The page number must be a positive number",
+	"exclusionsPageOutOfRange": "There are only {{.Pages}} page(s) of exclusions",
+	"forwardedFile":            "{{.Codename}} shared a file",
+	"forwardedFileName":        "file",
+	"forwardedFileTooLarge":    "{{.Codename}} shared a file too large to be forwarded",
+	"graphDone":                "Here is the anonymized Twin Lunch graph ({{.Nodes}} people, {{.Edges}} links)",
+	"graphError":               "I couldn't prepare the graph :warning:",
+	"graphFileTitle":           "Anonymized Twin Lunch graph",
+	"graphTooSmall":            "At least {{.Min}} people are needed in the history to export the graph",
+	"graphUsage":               "Use `/twinlunch-graph` or `/twinlunch-graph json`",
+	"history":                  "{{if .Entries}}<@{{.User}}> had a Twin Lunch with:\n\n{{range .Entries}}• <@{{.Partner}}>{{range .Others}} and <@{{.}}>{{end}} on {{.Date}}{{if .Round}} (round {{.Round}}){{end}}\n{{end}}{{else}}<@{{.User}}> never had a Twin Lunch{{end}}",
+	"historyUsage":             "Use `/twinlunch-history @someone`",
+	"homeAdmin":                "*Administration*",
+	"homeCancelButton":         "Cancel",
+	"homeClearButton":          "Remove all Twin Lunches",
+	"homeClearConfirm":         "All the current Twin Lunches will be removed",
+	"homeConfirmButton":        "Confirm",
+	"homeInPool":               "You signed up to be paired in the next rounds",
+	"homeJoinButton":           "Sign up",
+	"homeLeaveButton":          "Sign off",
+	"homeListButton":           "List Twin Lunches",
+	"homePaired":               "You have a Twin Lunch{{if .Round}} for round {{.Round}}{{end}}{{if gt .Partners 1}} with {{.Partners}} people{{end}}, send me a message to talk to your {{if gt .Partners 1}}partners{{else}}partner{{end}} :wave:",
+	"homeQuitButton":           "Leave my Twin Lunch",
+	"homeQuitConfirm":          "Your Twin Lunch will end, without telling that you're the one who left",
+	"homeTitle":                "Twin Lunch",
+	"homeUnpaired":             "You don't have a Twin Lunch right now",
+	"importInvalid":            "I didn't create any Twin Lunch:\n{{range .Errors}}\n• {{.}}{{end}}",
+	"importInvalidLine":        "expected two people, found {{.Count}}",
+	"importLineError":          "Line {{.Line}}: {{.Error}}",
+	"importUsage":              "Use `/twinlunch-import` followed by one pair per line: `@person1 @person2`, add `--dry-run` to preview the import",
+	"importUserTwice":          "<@{{.User}}> already appears on line {{.First}}",
+	"imported":                 "I {{if .DryRun}}would create{{else}}created{{end}} {{len .Imported}} Twin Lunch:\n\n{{range .Imported}}• <@{{.User1}}> and <@{{.User2}}>\n{{end}}{{range .Warnings}}\n{{.}}{{end}}{{if .DryRun}}\n_Dry run: nothing was saved and nobody was notified_{{end}}",
+	"inactivePartner":          "Your Twin Lunch hasn't been very active lately, your message was delivered anyway :hourglass_flowing_sand:",
+	"inspect":                  "Here is the state of <@{{.User}}>:\n\n{{if .Partner}}• In a Twin Lunch with <@{{.Partner}}>{{range .Others}} and <@{{.}}>{{end}}{{else}}• No Twin Lunch{{end}}\n{{if .CooldownUntil}}• On a break until {{.CooldownUntil}}\n{{end}}• {{.Count}} Twin Lunch in this program{{if .Max}} (maximum {{.Max}}){{end}}{{if .NoNotifications}}\n• Optional notifications turned off{{end}}{{if .PendingIntro}}\n• Intro message waiting to be sent{{end}}{{if .PriorityRounds}}\n• Prioritized for {{.PriorityRounds}} more round(s){{end}}",
+	"inspectUsage":             "You must give a person to inspect",
+	"intro":                    "{{if gt .Partners 1}}Hi! Your {{.Partners}} Twin Lunch have been chosen, you can chat with them in this conversation without revealing your identity, your messages will be forwarded to all of them :sunglasses:{{else}}Hi! Your Twin Lunch has been chosen, you can chat with them in this conversation without revealing your identity :sunglasses:{{end}}{{if .Slot}}\nYour Twin Lunch is waiting for you at table {{.Slot}}{{end}}",
+	"introFailed":              "I can't send the intro message to <@{{.User}}>, their Slack account may be deactivated. Messages from their Twin Lunch aren't forwarded to them, you can remove this Twin Lunch with `/twinlunch-remove`",
+	"joined":                   "Got it, you'll take part in the next Twin Lunch rounds :tada:\nUse `/twinlunch-leave` to stop taking part",
+	"languageAuto":             "Got it, I'll write to you in the language of your Slack account",
+	"languageSet":              "Got it, I'll write to you in English",
+	"left":                     "Got it, you won't take part in the next Twin Lunch rounds",
+	"list":                     "Here are the {{.Count}} Twin Lunch{{if gt .Pages 1}} (page {{.Page}}/{{.Pages}}){{end}}:\n\n{{range .Pairs}}• <@{{.User1}}> and <@{{.User2}}>{{range .Others}} and <@{{.}}>{{end}}\n{{end}}",
+	"listInvalidPage":          "The page number must be a positive number",
+	"listPageOutOfRange":       "There are only {{.Pages}} page(s) of Twin Lunch",
+	"memberQuit":               "A little change: someone left your Twin Lunch group, your messages are still forwarded to the other members",
+	"mentionedGroup":           "group",
+	"mentionedUser":            "someone",
+	"myHistory":                "You had {{.Count}} Twin Lunch{{if and .Start .End}} between {{.Start}} and {{.End}}{{else if .Start}} since {{.Start}}{{else if .End}} until {{.End}}{{end}}{{if .Rounds}}\nRounds: {{.Rounds}}{{end}}{{if .Max}}\nThe maximum is {{.Max}} Twin Lunch per person{{if .Capped}}\nYou reached the maximum, you won't be paired automatically anymore{{end}}{{end}}",
+	"nextRound":                "The next Twin Lunch round hasn't started yet, you'll get a message as soon as you have a Twin Lunch :hourglass_flowing_sand:{{if .URL}}\nTo sign up, go here: {{.URL}}{{end}}",
+	"noExclusions":             "There are no exclusions",
+	"noReactions":              "No one reacted with :{{.Emoji}}: to this message",
+	"noTwinLunch":              "Sorry, you don't have a Twin Lunch :crying_cat_face:",
+	"noTwinLunches":            "There are no Twin Lunch",
+	"notAdmin":                 "Sorry, you're not allowed to manage Twin Lunch :no_entry_sign:",
+	"notAnAdmin":               "<@{{.User}}> doesn't manage Twin Lunch",
+	"notPaired":                "<@{{.User1}}> and <@{{.User2}}> aren't in a Twin Lunch together",
+	"notificationsOff":         "Got it, I'll only send you essential messages from now on (and your Twin Lunch's)",
+	"notificationsOn":          "Got it, I'll send you all notifications again",
+	"numberedAdded":            "I created {{len .Created}} Twin Lunch:\n\n{{range .Created}}• Table {{.Slot}}: <@{{.User1}}> and <@{{.User2}}>\n{{end}}{{range .Warnings}}\n{{.}}{{end}}",
+	"numberedInvalidStart":     "The first table number must be a positive number",
+	"numberedSlotTaken":        "Table {{.Slot}} is already assigned to a Twin Lunch",
+	"numberedUsage":            "Use `/twinlunch-add-numbered [first number] @person1 @person2 @person3 @person4...`",
+	"numberedUserTwice":        "<@{{.User}}> appears more than once",
+	"operationCompleted":       "Operation {{.ID}} is already completed, there is nothing to resume",
+	"operationNotFound":        "I can't find operation {{.ID}}",
+	"operations":               "{{if .Operations}}Operations in progress:\n{{range .Operations}}• `{{.ID}}` from {{.CreatedAt}}: {{.Done}} Twin Lunch done, {{.Pending}} to create\n{{end}}Use `/twinlunch-resume-pair <id>` to resume one{{else}}There is no operation in progress{{end}}",
+	"pairFromReactionUsage":    "You must give a message link and an emoji",
+	"pairingSummary":           "{{if .Round}}Your Twin Lunch of round {{.Round}} is over!{{else}}Your Twin Lunch is over!{{end}}\n{{if ge .Days 0}}{{if eq .Days 0}}It lasted less than a day.{{else if eq .Days 1}}It lasted 1 day.{{else}}It lasted {{.Days}} days.{{end}}\n{{end}}{{if eq .Messages 0}}You didn't exchange any message, maybe next time!{{else if eq .Messages 1}}You exchanged 1 message.{{else}}You exchanged {{.Messages}} messages.{{end}}\nThanks for taking part :pray:{{if .URL}}\nTo take part in the next round, go here: {{.URL}}{{end}}",
+	"parsedMentions":           "{{if .Users}}I understood {{range $i, $user := .Users}}{{if $i}}, {{end}}<@{{$user}}>{{end}}{{else}}I didn't find anybody, people must be mentioned with @ and picked in the list{{end}}",
+	"partnerIntroPending":      "Your Twin Lunch hasn't received their intro message yet, I can't forward your messages to them for now. Try again a bit later :hourglass:",
+	"partnerSwapped":           "A little change: your Twin Lunch changed, you have a new partner :twisted_rightwards_arrows: Your next messages will be forwarded to them",
+	"partnerUnreachable":       "Your Twin Lunch can't be reached anymore, their Slack account was probably deactivated :disappointed: Your messages won't be delivered to them",
+	"placeholder":              "Working on it...",
+	"poolEmpty":                "No one signed up with `/twinlunch-join`",
+	"prefs":                    "Your notifications (reminders, summaries...) are {{if .Notifications}}on{{else}}off{{end}}\nI write to you {{if .Language}}in `{{.Language}}`{{else}}in the language of your Slack account{{end}}\nUse `/twinlunch-prefs notifications on|off` or `/twinlunch-prefs language fr|en|auto` to change them",
+	"prefsUsage":               "Use `/twinlunch-prefs notifications on|off` or `/twinlunch-prefs language fr|en|auto`",
+	"preview":                  "Here is what <@{{.User}}> sees:\n\n{{if .Paired}}• Intro message: _bip bip_ {{.Intro}}\n• Messages from their Twin Lunch come as “{{.PartnerCodename}}” with {{.Emojis}}\n• Their Twin Lunch sees their messages as “{{.Codename}}”{{else}}• When they write to the bot: _bip bip_ {{.NoTwinLunch}}{{end}}",
+	"previewUsage":             "You must give a person to preview their messages",
+	"prioritizeUsage":          "Use `/twinlunch-prioritize @person <number of rounds>`",
+	"prioritized":              "{{if .Rounds}}<@{{.User}}> will be paired first for {{.Rounds}} round(s){{else}}<@{{.User}}> isn't prioritized anymore{{end}}",
+	"quit":                     "Got it, I ended your Twin Lunch. I won't tell that you're the one who left",
+	"quitNoTwinLunch":          "You don't have a Twin Lunch right now, there's nothing to leave :slightly_smiling_face:",
+	"randomUsage":              "You must give at least two people to pair, add `--dry-run` to preview the pairings",
+	"reactionUsersError":       "I couldn't get the people who reacted",
+	"reactionsError":           "I couldn't read the reactions of this message, am I in the channel?",
+	"relayDisclaimer":          "Anonymous messages, please be respectful",
+	"relayThrottled":           "Easy there :turtle: You're sending a lot of messages at once, I'm not forwarding the next ones for a little while. Try again in a minute",
+	"relayedCopiesDeleted":     "This conversation is over, I deleted the {{.Count}} messages I relayed from your Twin Lunch",
+	"removeUsage":              "You must give two people to remove a Twin Lunch",
+	"removed":                  "I removed the Twin Lunch between <@{{.User1}}> and <@{{.User2}}>",
+	"report":                   ":rotating_light: Report from <@{{.User}}>{{if .PairingID}} about Twin Lunch {{.PairingID}}{{if .Round}} (round {{.Round}}){{end}}{{if .Since}}, ongoing since {{.Since}}{{end}}{{else}} (no ongoing Twin Lunch){{end}}:\n\n{{.Text}}",
+	"reportSent":               "Thanks, your report was sent to the organizers :pray:",
+	"reportTooSoon":            "You already sent a report recently, you can send another one from {{.Until}}",
+	"reportUsage":              "Use `/twinlunch-report <description of the issue>`",
+	"resumePairUsage":          "You must give the ID of the operation to resume, or nothing to list the operations in progress",
+	"reveal":                   "The round is over, {{if .Others}}your Twin Lunch were{{else}}your Twin Lunch was{{end}} <@{{.Partner}}>{{range .Others}} and <@{{.}}>{{end}}! :tada:",
+	"revealUsage":              "Use `/twinlunch-reveal`, or `/twinlunch-reveal keep` to keep the Twin Lunch",
+	"revealed":                 "I revealed {{.Count}} Twin Lunch{{if .Cleared}} and removed them :fire:{{end}}",
+	"round":                    "This is Twin Lunch round {{.Round}}",
+	"roundSet":                 "This is now Twin Lunch round {{.Round}}",
+	"roundUsage":               "Use `/twinlunch-round` to see the current round or `/twinlunch-round set <n>` to change it",
+	"scheduledPairing":         "It's time for the scheduled pairing :alarm_clock:\n\n{{.Report}}",
+	"skipCapped":               "reached the maximum of {{.Max}} Twin Lunch",
+	"skipCooldown":             "is on a break",
+	"skipInactive":             "no longer has an active Slack account",
+	"skipPaired":               "already has a Twin Lunch",
+	"stats":                    "{{if .Rounds}}Here are the Twin Lunch statistics:\n{{range .Rounds}}\n• {{if .Round}}Round {{.Round}}{{else}}No round{{end}}: {{.Pairs}} Twin Lunch, {{.Chatty}} with messages, {{.Messages}} messages{{end}}\n• Total: {{.Total.Pairs}} Twin Lunch, {{.Total.Chatty}} with messages, {{.Total.Messages}} messages{{else}}There hasn't been any Twin Lunch yet{{end}}{{if .Chatty}}\n\nThese ongoing Twin Lunch are chatting:{{range .Chatty}}\n• <@{{.User1}}> and <@{{.User2}}>{{range .Others}} and <@{{.}}>{{end}} ({{.Messages}} messages){{end}}{{end}}{{if .Silent}}\n\nThese ongoing Twin Lunch haven't exchanged any message yet:{{range .Silent}}\n• <@{{.User1}}> and <@{{.User2}}>{{range .Others}} and <@{{.}}>{{end}}{{end}}{{end}}",
+	"swapSamePair":             "These four people must be in two different Twin Lunches",
+	"swapUsage":                "Use `/twinlunch-swap @person1 @person2 @person3 @person4` to make the pairs 1-3 and 2-4 from the pairs 1-2 and 3-4",
+	"swapped":                  "I swapped the partners: <@{{.User1}}> is with <@{{.User3}}>, and <@{{.User2}}> with <@{{.User4}}>",
+	"topic":                    "Twin Lunch round {{.Round}} — {{.Pairs}} ongoing Twin Lunch",
+	"userHasNoTwinLunch":       "<@{{.User}}> doesn't have a Twin Lunch",
+	"userInactive":             "The Slack account of <@{{.User}}> is deactivated or invalid, I can't create a Twin Lunch for them",
+	"userInfoError":            "I couldn't check the Slack accounts of these people :warning:",
+	"whois":                    "<@{{.User}}> is in a Twin Lunch with <@{{.Partner}}>{{range .Others}} and <@{{.}}>{{end}}",
+	"whoisUsage":               "Use `/twinlunch-whois @someone`",
+}
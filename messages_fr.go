@@ -0,0 +1,173 @@
+package main
+
+var frMessages = map[string]string{
+	"activityDone":             "Voilà l'activité du {{.From}} au {{.To}} ({{.Count}} entrées)",
+	"activityEmpty":            "Il n'y a aucune activité sur cette période",
+	"activityError":            "Je n'ai pas réussi à préparer l'activité :warning:",
+	"activityFileTitle":        "Activité Twin Lunch du {{.From}} au {{.To}}",
+	"activityInvalidDate":      "Les dates doivent être au format AAAA-MM-JJ",
+	"activityInvalidRange":     "La date de fin doit être après la date de début",
+	"activityUsage":            "Tu dois donner une date de début et une date de fin (AAAA-MM-JJ)",
+	"addSameUser":              "Tu dois donner des personnes différentes pour créer un Twin Lunch",
+	"addTooMany":               "Tu peux donner au plus {{.Max}} personnes pour créer un Twin Lunch",
+	"addUsage":                 "Tu dois donner au moins deux personnes pour créer un Twin Lunch, ajoute `--dry-run` pour le prévisualiser",
+	"added":                    "{{if .DryRun}}Je mettrais{{else}}J'ai mis{{end}} en relation <@{{.User1}}> et <@{{.User2}}>{{range .Others}} et <@{{.}}>{{end}} pour leur Twin Lunch{{range .Warnings}}\n{{.}}{{end}}{{if .DryRun}}\n_Simulation : rien n'a été enregistré et personne n'a été prévenu_{{end}}",
+	"adminAddUsage":            "Utilise `/twinlunch-admin-add @quelqu'un`",
+	"adminAdded":               "<@{{.User}}> peut maintenant administrer les Twin Lunch",
+	"adminRemoveUsage":         "Utilise `/twinlunch-admin-remove @quelqu'un`",
+	"adminRemoved":             "<@{{.User}}> ne peut plus administrer les Twin Lunch",
+	"aliasFox":                 "Renard anonyme",
+	"aliasFrog":                "Grenouille anonyme",
+	"aliasHedgehog":            "Hérisson anonyme",
+	"aliasKoala":               "Koala anonyme",
+	"aliasOctopus":             "Pieuvre anonyme",
+	"aliasOtter":               "Loutre anonyme",
+	"aliasOwl":                 "Hibou anonyme",
+	"aliasPanda":               "Panda anonyme",
+	"aliasPenguin":             "Manchot anonyme",
+	"aliasTiger":               "Tigre anonyme",
+	"aliasTurtle":              "Tortue anonyme",
+	"aliasWolf":                "Loup anonyme",
+	"alreadyAdmin":             "<@{{.User}}> administre déjà les Twin Lunch",
+	"alreadyPaired":            "<@{{.User}}> a déjà un Twin Lunch",
+	"autoPairReport":           "{{if .Created}}{{if .DryRun}}Je créerais{{else}}J'ai créé{{end}} {{len .Created}} Twin Lunch :\n\n{{range .Created}}• <@{{.User1}}> et <@{{.User2}}>{{range .Others}} et <@{{.}}>{{end}}\n{{end}}{{else}}{{if .DryRun}}Je ne créerais{{else}}Je n'ai créé{{end}} aucun Twin Lunch\n{{end}}{{if .Left}}\nPersonne n'a pu être trouvé pour {{range $i, $user := .Left}}{{if $i}}, {{end}}<@{{$user}}>{{end}}\n{{end}}{{if .Skipped}}\nCes personnes n'ont pas été prises en compte :\n{{range .Skipped}}• <@{{.User}}> {{.Reason}}\n{{end}}{{end}}{{if .Repeats}}\nFaute de mieux, ces personnes ont déjà eu un Twin Lunch ensemble lors des {{.RepeatRounds}} derniers tours :\n{{range .Repeats}}• <@{{.User1}}> et <@{{.User2}}>{{range .Others}} et <@{{.}}>{{end}}\n{{end}}{{end}}{{if .OperationID}}\n:warning: {{.Pending}} Twin Lunch n'ont pas pu être créés, utilise `/twinlunch-resume-pair {{.OperationID}}` pour réessayer\n{{end}}{{if .DryRun}}\n_Simulation : rien n'a été enregistré et personne n'a été prévenu_{{end}}",
+	"broadcast":                ":mega: {{.Text}}",
+	"broadcastNobody":          "Personne n'a de Twin Lunch en cours, je n'ai rien envoyé",
+	"broadcastSent":            "J'envoie ton annonce à {{.Count}} personne(s)",
+	"broadcastUsage":           "Utilise `/twinlunch-broadcast message`",
+	"cappedWarning":            ":warning: <@{{.User}}> a déjà eu {{.Count}} Twin Lunch sur ce programme (maximum {{.Max}})",
+	"cleared":                  "J'ai supprimé tous les Twin Lunch :fire:",
+	"codenameInvalidChars":     "Le nom de code ne peut pas contenir les caractères <, > ou @",
+	"codenameLength":           "Le nom de code doit faire entre {{.Min}} et {{.Max}} caractères",
+	"codenameReserved":         "Le nom de code « {{.Codename}} » est réservé",
+	"codenameSet":              "Ton nom de code est maintenant « {{.Codename}} »",
+	"codenameSetFor":           "Le nom de code de <@{{.User}}> est maintenant « {{.Codename}} »",
+	"codenameTaken":            "Le nom de code « {{.Codename}} » est déjà utilisé dans ce Twin Lunch",
+	"commandReceived":          "C'est noté, je traite ta demande et je te réponds en message privé :robot_face:",
+	"configuredAdmin":          "<@{{.User}}> est dans `TWIN_LUNCH_ADMINS`, il faut modifier la configuration pour lui retirer les droits",
+	"cooldownWarning":          ":warning: <@{{.User}}> est en période de pause jusqu'au {{.Until}}",
+	"datastoreError":           "Je n'ai pas réussi à accéder à la base de données, réessaie plus tard :warning:",
+	"defaultCodename":          "Ton Twin Lunch",
+	"ended":                    "{{if .Round}}Ton Twin Lunch du tour n°{{.Round}} est terminé{{else}}Ton Twin Lunch est terminé{{end}}, tes messages ne lui seront plus transmis :wave:",
+	"excludeSameUser":          "Tu dois donner deux personnes différentes pour créer une exclusion",
+	"excludeUsage":             "Tu dois donner deux personnes pour créer une exclusion",
+	"excluded":                 "<@{{.User1}}> et <@{{.User2}}> ne seront plus mis en relation",
+	"excludedPair":             "<@{{.User1}}> et <@{{.User2}}> ne peuvent pas être mis en relation{{if .Reason}} : {{.Reason}}{{end}}",
+	"exclusions":               "Voilà la liste des exclusions (page {{.Page}}/{{.Pages}}) :\n\n{{range .Exclusions}}• <@{{.User1}}> et <@{{.User2}}>, par <@{{.CreatedBy}}> le {{.CreatedAt.Format \"2006-01-02\"}}{{if .Reason}} : {{.Reason}}{{end}}\n{{end}}",
+	"exclusionsInvalidPage":    "Le numéro de page doit être un nombre positif",
+	"exclusionsPageOutOfRange": "Il n'y a que {{.Pages}} page(s) d'exclusions",
+	"forwardedFile":            "{{.Codename}} a partagé un fichier",
+	"forwardedFileName":        "fichier",
+	"forwardedFileTooLarge":    "{{.Codename}} a partagé un fichier trop volumineux pour être transmis",
+	"graphDone":                "Voilà le graphe anonymisé des Twin Lunch ({{.Nodes}} personnes, {{.Edges}} liens)",
+	"graphError":               "Je n'ai pas réussi à préparer le graphe :warning:",
+	"graphFileTitle":           "Graphe anonymisé des Twin Lunch",
+	"graphTooSmall":            "Il faut au moins {{.Min}} personnes dans l'historique pour exporter le graphe",
+	"graphUsage":               "Utilise `/twinlunch-graph` ou `/twinlunch-graph json`",
+	"history":                  "{{if .Entries}}<@{{.User}}> a eu un Twin Lunch avec :\n\n{{range .Entries}}• <@{{.Partner}}>{{range .Others}} et <@{{.}}>{{end}} le {{.Date}}{{if .Round}} (tour {{.Round}}){{end}}\n{{end}}{{else}}<@{{.User}}> n'a jamais eu de Twin Lunch{{end}}",
+	"historyUsage":             "Utilise `/twinlunch-history @quelqu'un`",
+	"homeAdmin":                "*Administration*",
+	"homeCancelButton":         "Annuler",
+	"homeClearButton":          "Supprimer tous les Twin Lunch",
+	"homeClearConfirm":         "Tous les Twin Lunch en cours seront supprimés",
+	"homeConfirmButton":        "Confirmer",
+	"homeInPool":               "Tu es inscrit·e pour être mis·e en relation aux prochains tours",
+	"homeJoinButton":           "M'inscrire",
+	"homeLeaveButton":          "Me désinscrire",
+	"homeListButton":           "Lister les Twin Lunch",
+	"homePaired":               "Tu as un Twin Lunch en cours{{if .Round}} pour le tour {{.Round}}{{end}}{{if gt .Partners 1}} avec {{.Partners}} personnes{{end}}, envoie-moi un message pour parler à {{if gt .Partners 1}}tes partenaires{{else}}ton partenaire{{end}} :wave:",
+	"homeQuitButton":           "Quitter mon Twin Lunch",
+	"homeQuitConfirm":          "Ton Twin Lunch sera terminé, sans dire que c'est toi qui l'as quitté",
+	"homeTitle":                "Twin Lunch",
+	"homeUnpaired":             "Tu n'as pas de Twin Lunch en cours",
+	"importInvalid":            "Je n'ai créé aucun Twin Lunch :\n{{range .Errors}}\n• {{.}}{{end}}",
+	"importInvalidLine":        "deux personnes attendues, {{.Count}} trouvée(s)",
+	"importLineError":          "Ligne {{.Line}} : {{.Error}}",
+	"importUsage":              "Utilise `/twinlunch-import` suivi d'une paire par ligne : `@personne1 @personne2`, ajoute `--dry-run` pour prévisualiser l'import",
+	"importUserTwice":          "<@{{.User}}> apparaît déjà ligne {{.First}}",
+	"imported":                 "{{if .DryRun}}Je créerais{{else}}J'ai créé{{end}} {{len .Imported}} Twin Lunch :\n\n{{range .Imported}}• <@{{.User1}}> et <@{{.User2}}>\n{{end}}{{range .Warnings}}\n{{.}}{{end}}{{if .DryRun}}\n_Simulation : rien n'a été enregistré et personne n'a été prévenu_{{end}}",
+	"inactivePartner":          "Ton Twin Lunch n'a pas été très actif récemment, ton message lui a bien été transmis :hourglass_flowing_sand:",
+	"inspect":                  "Voilà l'état de <@{{.User}}> :\n\n{{if .Partner}}• En Twin Lunch avec <@{{.Partner}}>{{range .Others}} et <@{{.}}>{{end}}{{else}}• Pas de Twin Lunch{{end}}\n{{if .CooldownUntil}}• En période de pause jusqu'au {{.CooldownUntil}}\n{{end}}• {{.Count}} Twin Lunch sur ce programme{{if .Max}} (maximum {{.Max}}){{end}}{{if .NoNotifications}}\n• Notifications optionnelles désactivées{{end}}{{if .PendingIntro}}\n• Message d'accueil en attente d'envoi{{end}}{{if .PriorityRounds}}\n• Prioritaire pour encore {{.PriorityRounds}} tour(s){{end}}",
+	"inspectUsage":             "Tu dois donner une personne à inspecter",
+	"intro":                    "{{if gt .Partners 1}}Salut ! Tes {{.Partners}} Twin Lunch ont été choisis, tu peux discuter avec eux dans cette conversation sans révéler ton identité, tes messages leur seront transmis à tous :sunglasses:{{else}}Salut ! Ton Twin Lunch a été choisi, tu peux discuter avec lui ou elle dans cette conversation sans révéler ton identité :sunglasses:{{end}}{{if .Slot}}\nTon Twin Lunch t'attend à la table {{.Slot}}{{end}}",
+	"introFailed":              "Je n'arrive pas à envoyer son message d'intro à <@{{.User}}>, son compte Slack est peut-être désactivé. Les messages de son Twin Lunch ne lui sont pas transmis, tu peux supprimer ce Twin Lunch avec `/twinlunch-remove`",
+	"joined":                   "C'est noté, tu participeras aux prochains tours de Twin Lunch :tada:\nUtilise `/twinlunch-leave` pour ne plus participer",
+	"languageAuto":             "C'est noté, je t'écrirai dans la langue de ton compte Slack",
+	"languageSet":              "C'est noté, je t'écrirai en français",
+	"left":                     "C'est noté, tu ne participeras plus aux prochains tours de Twin Lunch",
+	"list":                     "Voilà la liste des {{.Count}} Twin Lunch{{if gt .Pages 1}} (page {{.Page}}/{{.Pages}}){{end}} :\n\n{{range .Pairs}}• <@{{.User1}}> et <@{{.User2}}>{{range .Others}} et <@{{.}}>{{end}}\n{{end}}",
+	"listInvalidPage":          "Le numéro de page doit être un nombre positif",
+	"listPageOutOfRange":       "Il n'y a que {{.Pages}} page(s) de Twin Lunch",
+	"memberQuit":               "Petit changement : quelqu'un a quitté ton groupe de Twin Lunch, tes messages sont toujours transmis aux autres membres",
+	"mentionedGroup":           "groupe",
+	"mentionedUser":            "quelqu'un",
+	"myHistory":                "Tu as eu {{.Count}} Twin Lunch{{if and .Start .End}} entre le {{.Start}} et le {{.End}}{{else if .Start}} depuis le {{.Start}}{{else if .End}} jusqu'au {{.End}}{{end}}{{if .Rounds}}\nTours : {{.Rounds}}{{end}}{{if .Max}}\nLe maximum est de {{.Max}} Twin Lunch par personne{{if .Capped}}\nTu as atteint le maximum, tu ne seras plus mis·e en relation automatiquement{{end}}{{end}}",
+	"nextRound":                "Le prochain tour de Twin Lunch n'a pas encore commencé, tu recevras un message dès que tu auras un Twin Lunch :hourglass_flowing_sand:{{if .URL}}\nPour t'inscrire, c'est par ici : {{.URL}}{{end}}",
+	"noExclusions":             "Il n'y a aucune exclusion",
+	"noReactions":              "Personne n'a réagi avec :{{.Emoji}}: à ce message",
+	"noTwinLunch":              "Désolé tu n'as pas de Twin Lunch :crying_cat_face:",
+	"noTwinLunches":            "Il n'y a aucun Twin Lunch",
+	"notAdmin":                 "Désolé mais tu n'as pas les droits pour administrer les Twin Lunch :no_entry_sign:",
+	"notAnAdmin":               "<@{{.User}}> n'administre pas les Twin Lunch",
+	"notPaired":                "<@{{.User1}}> et <@{{.User2}}> ne sont pas en Twin Lunch ensemble",
+	"notificationsOff":         "C'est noté, je ne t'enverrai plus que les messages essentiels (et ceux de ton Twin Lunch)",
+	"notificationsOn":          "C'est noté, je t'enverrai à nouveau toutes les notifications",
+	"numberedAdded":            "J'ai créé {{len .Created}} Twin Lunch :\n\n{{range .Created}}• Table {{.Slot}} : <@{{.User1}}> et <@{{.User2}}>\n{{end}}{{range .Warnings}}\n{{.}}{{end}}",
+	"numberedInvalidStart":     "Le premier numéro de table doit être un nombre positif",
+	"numberedSlotTaken":        "La table {{.Slot}} est déjà attribuée à un Twin Lunch",
+	"numberedUsage":            "Utilise `/twinlunch-add-numbered [premier numéro] @personne1 @personne2 @personne3 @personne4...`",
+	"numberedUserTwice":        "<@{{.User}}> apparaît plusieurs fois",
+	"operationCompleted":       "L'opération {{.ID}} est déjà terminée, il n'y a rien à reprendre",
+	"operationNotFound":        "Je ne trouve pas l'opération {{.ID}}",
+	"operations":               "{{if .Operations}}Opérations en cours :\n{{range .Operations}}• `{{.ID}}` du {{.CreatedAt}} : {{.Done}} Twin Lunch traités, {{.Pending}} à créer\n{{end}}Utilise `/twinlunch-resume-pair <id>` pour en reprendre une{{else}}Il n'y a aucune opération en cours{{end}}",
+	"pairFromReactionUsage":    "Tu dois donner le lien d'un message et un emoji",
+	"pairingSummary":           "{{if .Round}}Ton Twin Lunch du tour n°{{.Round}} est terminé !{{else}}Ton Twin Lunch est terminé !{{end}}\n{{if ge .Days 0}}{{if eq .Days 0}}Il a duré moins d'un jour.{{else if eq .Days 1}}Il a duré 1 jour.{{else}}Il a duré {{.Days}} jours.{{end}}\n{{end}}{{if eq .Messages 0}}Vous n'avez pas échangé de message, ce sera peut-être pour la prochaine fois !{{else if eq .Messages 1}}Vous avez échangé 1 message.{{else}}Vous avez échangé {{.Messages}} messages.{{end}}\nMerci d'avoir participé :pray:{{if .URL}}\nPour participer au prochain tour, c'est par ici : {{.URL}}{{end}}",
+	"parsedMentions":           "{{if .Users}}J'ai compris {{range $i, $user := .Users}}{{if $i}}, {{end}}<@{{$user}}>{{end}}{{else}}Je n'ai trouvé personne, les personnes doivent être mentionnées avec @ et choisies dans la liste{{end}}",
+	"partnerIntroPending":      "Ton Twin Lunch n'a pas encore reçu son message d'intro, je ne peux pas encore lui transmettre tes messages. Réessaie un peu plus tard :hourglass:",
+	"partnerSwapped":           "Petit changement : ton Twin Lunch a changé, tu as un nouveau partenaire :twisted_rightwards_arrows: Tes prochains messages lui seront transmis",
+	"partnerUnreachable":       "Ton Twin Lunch n'est plus joignable, son compte Slack a sans doute été désactivé :disappointed: Tes messages ne lui seront plus transmis",
+	"placeholder":              "Je prépare ça...",
+	"poolEmpty":                "Personne ne s'est inscrit avec `/twinlunch-join`",
+	"prefs":                    "Tes notifications (rappels, résumés...) sont {{if .Notifications}}activées{{else}}désactivées{{end}}\nJe t'écris {{if .Language}}en `{{.Language}}`{{else}}dans la langue de ton compte Slack{{end}}\nUtilise `/twinlunch-prefs notifications on|off` ou `/twinlunch-prefs language fr|en|auto` pour les changer",
+	"prefsUsage":               "Utilise `/twinlunch-prefs notifications on|off` ou `/twinlunch-prefs language fr|en|auto`",
+	"preview":                  "Voilà ce que voit <@{{.User}}> :\n\n{{if .Paired}}• Message d'accueil : _bip bip_ {{.Intro}}\n• Les messages de son Twin Lunch arrivent sous le nom « {{.PartnerCodename}} » avec {{.Emojis}}\n• Son Twin Lunch voit ses messages sous le nom « {{.Codename}} »{{else}}• Quand il ou elle écrit au bot : _bip bip_ {{.NoTwinLunch}}{{end}}",
+	"previewUsage":             "Tu dois donner une personne pour prévisualiser ses messages",
+	"prioritizeUsage":          "Utilise `/twinlunch-prioritize @personne <nombre de tours>`",
+	"prioritized":              "{{if .Rounds}}<@{{.User}}> sera mis·e en relation en priorité pendant {{.Rounds}} tour(s){{else}}<@{{.User}}> n'est plus prioritaire{{end}}",
+	"quit":                     "C'est noté, j'ai mis fin à ton Twin Lunch. Je ne dirai pas que c'est toi qui l'as quitté",
+	"quitNoTwinLunch":          "Tu n'as pas de Twin Lunch en cours, il n'y a rien à quitter :slightly_smiling_face:",
+	"randomUsage":              "Tu dois donner au moins deux personnes à mettre en relation, ajoute `--dry-run` pour prévisualiser les paires",
+	"reactionUsersError":       "Je n'ai pas réussi à récupérer les personnes qui ont réagi",
+	"reactionsError":           "Je n'ai pas réussi à lire les réactions de ce message, est-ce que je suis bien dans le canal ?",
+	"relayDisclaimer":          "Messages anonymes, sois respectueux·se",
+	"relayThrottled":           "Doucement :turtle: Tu envoies beaucoup de messages d'un coup, je ne transmets plus les suivants pendant un petit moment. Réessaie dans une minute",
+	"relayedCopiesDeleted":     "Cette conversation est terminée, j'ai supprimé les {{.Count}} messages que je t'avais transmis de ton Twin Lunch",
+	"removeUsage":              "Tu dois donner deux personnes pour supprimer un Twin Lunch",
+	"removed":                  "J'ai supprimé le Twin Lunch entre <@{{.User1}}> et <@{{.User2}}>",
+	"report":                   ":rotating_light: Signalement de <@{{.User}}>{{if .PairingID}} sur le Twin Lunch {{.PairingID}}{{if .Round}} (tour n°{{.Round}}){{end}}{{if .Since}}, en cours depuis le {{.Since}}{{end}}{{else}} (sans Twin Lunch en cours){{end}} :\n\n{{.Text}}",
+	"reportSent":               "Merci, ton signalement a bien été transmis aux organisateurs :pray:",
+	"reportTooSoon":            "Tu as déjà envoyé un signalement récemment, tu pourras en envoyer un autre à partir du {{.Until}}",
+	"reportUsage":              "Utilise `/twinlunch-report <description du problème>`",
+	"resumePairUsage":          "Tu dois donner l'identifiant de l'opération à reprendre, ou rien pour lister les opérations en cours",
+	"reveal":                   "C'est la fin du tour, {{if .Others}}tes Twin Lunch étaient{{else}}ton Twin Lunch était{{end}} <@{{.Partner}}>{{range .Others}} et <@{{.}}>{{end}} ! :tada:",
+	"revealUsage":              "Utilise `/twinlunch-reveal` ou `/twinlunch-reveal keep` pour garder les Twin Lunch",
+	"revealed":                 "J'ai révélé {{.Count}} Twin Lunch{{if .Cleared}} et je les ai supprimés :fire:{{end}}",
+	"round":                    "C'est le tour n°{{.Round}} des Twin Lunch",
+	"roundSet":                 "C'est maintenant le tour n°{{.Round}} des Twin Lunch",
+	"roundUsage":               "Utilise `/twinlunch-round` pour voir le tour actuel ou `/twinlunch-round set <n>` pour le changer",
+	"scheduledPairing":         "C'est l'heure de la mise en relation automatique :alarm_clock:\n\n{{.Report}}",
+	"skipCapped":               "a atteint le maximum de {{.Max}} Twin Lunch",
+	"skipCooldown":             "est en période de pause",
+	"skipInactive":             "n'a plus de compte Slack actif",
+	"skipPaired":               "a déjà un Twin Lunch",
+	"stats":                    "{{if .Rounds}}Voilà les statistiques des Twin Lunch :\n{{range .Rounds}}\n• {{if .Round}}Tour n°{{.Round}}{{else}}Sans tour{{end}} : {{.Pairs}} Twin Lunch, {{.Chatty}} avec des messages, {{.Messages}} messages{{end}}\n• Total : {{.Total.Pairs}} Twin Lunch, {{.Total.Chatty}} avec des messages, {{.Total.Messages}} messages{{else}}Il n'y a pas encore eu de Twin Lunch{{end}}{{if .Chatty}}\n\nCes Twin Lunch en cours discutent :{{range .Chatty}}\n• <@{{.User1}}> et <@{{.User2}}>{{range .Others}} et <@{{.}}>{{end}} ({{.Messages}} messages){{end}}{{end}}{{if .Silent}}\n\nCes Twin Lunch en cours n'ont pas encore échangé de message :{{range .Silent}}\n• <@{{.User1}}> et <@{{.User2}}>{{range .Others}} et <@{{.}}>{{end}}{{end}}{{end}}",
+	"swapSamePair":             "Ces quatre personnes doivent former deux Twin Lunch différents",
+	"swapUsage":                "Utilise `/twinlunch-swap @personne1 @personne2 @personne3 @personne4` pour former les paires 1-3 et 2-4 à partir des paires 1-2 et 3-4",
+	"swapped":                  "J'ai échangé les partenaires : <@{{.User1}}> est avec <@{{.User3}}>, et <@{{.User2}}> avec <@{{.User4}}>",
+	"topic":                    "Twin Lunch tour n°{{.Round}} — {{.Pairs}} Twin Lunch en cours",
+	"userHasNoTwinLunch":       "<@{{.User}}> n'a pas de Twin Lunch",
+	"userInactive":             "Le compte Slack de <@{{.User}}> est désactivé ou invalide, je ne peux pas lui créer de Twin Lunch",
+	"userInfoError":            "Je n'ai pas réussi à vérifier les comptes Slack des personnes :warning:",
+	"whois":                    "<@{{.User}}> est en Twin Lunch avec <@{{.Partner}}>{{range .Others}} et <@{{.}}>{{end}}",
+	"whoisUsage":               "Utilise `/twinlunch-whois @quelqu'un`",
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestMessageTo(t *testing.T) {
+	var fs, _ = setupFakes(t)
+
+	slackLocalesMu.Lock()
+	var savedLocales = slackLocales
+	slackLocales = make(map[string]string)
+	slackLocalesMu.Unlock()
+	t.Cleanup(func() {
+		slackLocalesMu.Lock()
+		slackLocales = savedLocales
+		slackLocalesMu.Unlock()
+	})
+
+	handlePrefsCommand(slack.SlashCommand{Command: "/twinlunch-prefs", UserID: "UEN", Text: "language en"})
+	rememberSlackLocale("USLACKEN", "en-US")
+	rememberSlackLocale("USLACKDE", "de-DE")
+
+	var tests = []struct {
+		user string
+		want string
+	}{
+		{"UEN", enMessages["notAdmin"]},
+		{"USLACKEN", enMessages["notAdmin"]},
+		{"USLACKDE", messageCatalogs[messageLanguage]["notAdmin"]},
+		{"UUNKNOWN", messageCatalogs[messageLanguage]["notAdmin"]},
+	}
+
+	for _, test := range tests {
+		if text := messageTo(test.user, "notAdmin", nil); text != test.want {
+			t.Errorf("messageTo(%s) = %q, want %q", test.user, text, test.want)
+		}
+	}
+
+	deliveries.Wait()
+	checkMessages(t, fs, map[string][]string{dmChannel("UEN"): {enMessages["languageSet"]}})
+}
+
+func TestMessageCatalogs(t *testing.T) {
+	for lang, catalog := range messageCatalogs {
+		for id := range frMessages {
+			if _, ok := catalog[id]; !ok {
+				t.Errorf("message %q is missing from the %q catalog", id, lang)
+			}
+		}
+		for id := range catalog {
+			if _, ok := frMessages[id]; !ok {
+				t.Errorf("message %q of the %q catalog is unknown", id, lang)
+			}
+		}
+	}
+}
+
+func TestLoadMessageTemplates(t *testing.T) {
+	var savedTemplates = messageTemplates
+	t.Cleanup(func() { messageTemplates = savedTemplates })
+
+	var tests = []struct {
+		name     string
+		content  string
+		wantLogs []string
+		want     map[string]string
+	}{
+		{
+			name:    "valid override",
+			content: `{"notAdmin": "Admins only, <@{{.User}}>"}`,
+			want: map[string]string{
+				"notAdmin":      "Admins only, <@U1>",
+				"alreadyPaired": message("alreadyPaired", messageData{"User": "U1"}),
+			},
+		},
+		{
+			name:     "malformed template",
+			content:  `{"notAdmin": "Admins only, <@{{.User}>", "alreadyPaired": "<@{{.User}}> is taken"}`,
+			wantLogs: []string{`ignoring invalid message template "notAdmin"`},
+			want: map[string]string{
+				"notAdmin":      message("notAdmin", nil),
+				"alreadyPaired": "<@U1> is taken",
+			},
+		},
+		{
+			name:     "unknown message",
+			content:  `{"notAMessage": "hello"}`,
+			wantLogs: []string{`ignoring unknown message template "notAMessage"`},
+			want:     map[string]string{"notAdmin": message("notAdmin", nil)},
+		},
+		{
+			name:     "missing field at render time",
+			content:  `{"alreadyPaired": "<@{{.Admin}}> is taken"}`,
+			wantLogs: []string{`error rendering message template "alreadyPaired", using default`},
+			want:     map[string]string{"alreadyPaired": message("alreadyPaired", messageData{"User": "U1"})},
+		},
+		{
+			name:     "malformed file",
+			content:  `{"notAdmin": `,
+			wantLogs: []string{"error decoding message templates, using defaults"},
+			want:     map[string]string{"notAdmin": message("notAdmin", nil)},
+		},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var logs = captureLogs(t)
+			messageTemplates = defaultTemplates
+
+			var path = filepath.Join(t.TempDir(), "messages.json")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			loadMessageTemplates(path)
+
+			for id, want := range test.want {
+				if text := message(id, messageData{"User": "U1"}); text != want {
+					t.Errorf("message(%s) = %q, want %q", id, text, want)
+				}
+			}
+			for _, want := range test.wantLogs {
+				if output := logs.String(); !strings.Contains(output, want) {
+					t.Errorf("logs = %q, want %q", output, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+type numberedPairData struct {
+	Slot         int
+	User1, User2 string
+}
+
+// handleAddNumberedCommand creates several pairings with incrementing table numbers,
+// starting after the highest number in use unless a first number is given.
+func handleAddNumberedCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+	var start = 0
+
+	if len(args) != 0 && !userRegexp.MatchString(args[0]) {
+		var err error
+		if start, err = strconv.Atoi(args[0]); err != nil || start < 1 {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "numberedInvalidStart", nil))
+			return
+		}
+	}
+
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) == 0 || len(matches)%2 != 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "numberedUsage", nil))
+		return
+	}
+
+	var slots = make(map[int]struct{})
+	var maxSlot = 0
+	for _, twinLunch := range twinLunches.List() {
+		if twinLunch.Slot == 0 {
+			continue
+		}
+		slots[twinLunch.Slot] = struct{}{}
+		if twinLunch.Slot > maxSlot {
+			maxSlot = twinLunch.Slot
+		}
+	}
+
+	if start == 0 {
+		start = maxSlot + 1
+	}
+
+	var pairs = make([]numberedPairData, 0, len(matches)/2)
+	var seen = make(map[string]struct{}, len(matches))
+
+	for i := 0; i < len(matches); i += 2 {
+		var pair = numberedPairData{start + i/2, matches[i][1], matches[i+1][1]}
+
+		for _, user := range []string{pair.User1, pair.User2} {
+			if _, ok := seen[user]; ok {
+				sendBotMessageToUser(command.UserID, messageTo(command.UserID, "numberedUserTwice", messageData{"User": user}))
+				return
+			}
+			seen[user] = struct{}{}
+
+			if _, ok := twinLunches.Get(user); ok {
+				sendBotMessageToUser(command.UserID, messageTo(command.UserID, "alreadyPaired", messageData{"User": user}))
+				return
+			}
+		}
+
+		if _, ok := slots[pair.Slot]; ok {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "numberedSlotTaken", messageData{"Slot": pair.Slot}))
+			return
+		}
+
+		if exclusion, err := getExclusion(pair.User1, pair.User2); err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		} else if exclusion != nil {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "excludedPair", messageData{"User1": pair.User1, "User2": pair.User2, "Reason": exclusion.Reason}))
+			return
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	var users = make([]string, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		users = append(users, pair.User1, pair.User2)
+	}
+	if user, err := inactiveUser(users); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userInfoError", nil))
+		return
+	} else if user != "" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userInactive", messageData{"User": user}))
+		return
+	}
+
+	var warnings []string
+	for _, pair := range pairs {
+		var pairWarnings, err = pairingWarnings(command.UserID, pair.User1, pair.User2)
+		if err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		}
+		warnings = append(warnings, pairWarnings...)
+	}
+
+	var created = make([]numberedPairData, 0, len(pairs))
+	for _, pair := range pairs {
+		if _, err := createTwinLunch([]string{pair.User1, pair.User2}, pair.Slot, command.UserID); err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			break
+		}
+		created = append(created, pair)
+	}
+
+	if len(created) != 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "numberedAdded", messageData{"Created": created, "Warnings": warnings}))
+	}
+}
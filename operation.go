@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+// TwinLunchOperation records the progress of a bulk pairing, so that it can be resumed if it is interrupted.
+// The groups are stored as comma separated user IDs, datastore can't store nested slices.
+type TwinLunchOperation struct {
+	Key       *datastore.Key `datastore:"__key__"`
+	Admin     string
+	CreatedAt time.Time
+	Pending   []string `datastore:",noindex"`
+	Done      []string `datastore:",noindex"`
+	Completed bool
+}
+
+type operationData struct {
+	ID            int64
+	CreatedAt     string
+	Done, Pending int
+}
+
+// startPairingOperation saves the groups of a bulk pairing before they are created.
+func startPairingOperation(admin string, groups [][]string) (*TwinLunchOperation, error) {
+	var operation = &TwinLunchOperation{Admin: admin, CreatedAt: time.Now(), Completed: len(groups) == 0}
+	for _, group := range groups {
+		operation.Pending = append(operation.Pending, strings.Join(group, ","))
+	}
+
+	var keys, err = allocateKeys("TwinLunchOperation", nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	operation.Key = keys[0]
+
+	if err := saveOperation(operation); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+func saveOperation(operation *TwinLunchOperation) error {
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, operation.Key, operation)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error writing operation in datastore: %w", err)
+	}
+	return nil
+}
+
+// runPairingOperation creates the pending groups of operation, saving its progress after each group.
+// The members who were paired meanwhile are skipped, they are the ones whose group was created before an interruption,
+// the other members of their group are returned in left. The groups which fail stay pending.
+func runPairingOperation(operation *TwinLunchOperation) (created [][]string, left []string) {
+	var tried = operation.Pending
+	var failed []string
+
+	for i, group := range tried {
+		var members []string
+		for _, user := range strings.Split(group, ",") {
+			if _, ok := twinLunches.Get(user); !ok {
+				members = append(members, user)
+			}
+		}
+
+		if len(members) >= 2 {
+			if _, err := createTwinLunch(members, 0, operation.Admin); err != nil {
+				logger.With("operation", operation.Key.ID).Println(err)
+				failed = append(failed, group)
+				continue
+			}
+			created = append(created, members)
+		} else {
+			left = append(left, members...)
+		}
+
+		// the failed groups and the ones not tried yet stay pending
+		operation.Done = append(operation.Done, group)
+		operation.Pending = append(append([]string(nil), failed...), tried[i+1:]...)
+		if err := saveOperation(operation); err != nil {
+			logger.With("operation", operation.Key.ID).Println(err)
+		}
+	}
+
+	operation.Pending = failed
+	operation.Completed = len(failed) == 0
+	if err := saveOperation(operation); err != nil {
+		logger.With("operation", operation.Key.ID).Println(err)
+	}
+
+	return created, left
+}
+
+func getOperation(id int64) (*TwinLunchOperation, error) {
+	var operation TwinLunchOperation
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Get(ctx, datastore.IDKey("TwinLunchOperation", id, nil), &operation)
+	}); errors.Is(err, datastore.ErrNoSuchEntity) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading operation from datastore: %w", err)
+	}
+
+	return &operation, nil
+}
+
+func getOperationsInProgress() ([]*TwinLunchOperation, error) {
+	var operations []*TwinLunchOperation
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		operations = nil
+		var _, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchOperation").Filter("Completed =", false), &operations)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("error reading operations from datastore: %w", err)
+	}
+
+	return operations, nil
+}
+
+// handleResumePairCommand lists the bulk pairings in progress, or resumes one of them.
+func handleResumePairCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+
+	if len(args) == 0 {
+		var operations, err = getOperationsInProgress()
+		if err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		}
+
+		var data = make([]operationData, 0, len(operations))
+		for _, operation := range operations {
+			data = append(data, operationData{operation.Key.ID, operation.CreatedAt.Format(cooldownLayout), len(operation.Done), len(operation.Pending)})
+		}
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "operations", messageData{"Operations": data}))
+		return
+	}
+
+	var id, err = strconv.ParseInt(args[0], 10, 64)
+	if len(args) != 1 || err != nil {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "resumePairUsage", nil))
+		return
+	}
+
+	operation, err := getOperation(id)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	} else if operation == nil {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "operationNotFound", messageData{"ID": id}))
+		return
+	}
+
+	if len(operation.Pending) == 0 {
+		if !operation.Completed {
+			operation.Completed = true
+			if err := saveOperation(operation); err != nil {
+				commandLogger(command).Println(err)
+			}
+		}
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "operationCompleted", messageData{"ID": id}))
+		return
+	}
+
+	var channel, ts = sendPlaceholderToUser(command.UserID)
+
+	var created, left = runPairingOperation(operation)
+
+	replacePlaceholder(command.UserID, channel, ts, autoPairReport(command.UserID, operation, created, left, nil, nil, false))
+}
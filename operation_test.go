@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+func storedOperation(t *testing.T, fd *fakeDatastore, id int64) *TwinLunchOperation {
+	t.Helper()
+
+	var operation TwinLunchOperation
+	if err := fd.Get(context.Background(), datastore.IDKey("TwinLunchOperation", id, nil), &operation); err != nil {
+		t.Fatal(err)
+	}
+	return &operation
+}
+
+func sortedMembers(members [][]string) []string {
+	var joined []string
+	for _, group := range members {
+		var group = append([]string(nil), group...)
+		sort.Strings(group)
+		joined = append(joined, strings.Join(group, ","))
+	}
+	sort.Strings(joined)
+	return joined
+}
+
+func TestResumePairing(t *testing.T) {
+	var fs, fd = setupFakes(t)
+
+	// the operation and the first group are saved, the second group fails
+	fd.failNext("AllocateIDs", nil, nil, errors.New("datastore is down"))
+
+	var reports []string
+	autoPair("UADMIN", []string{"U1", "U2", "U3", "U4"}, false, func(render func(admin string) string) { reports = append(reports, render("UADMIN")) })
+
+	var operations, err = getOperationsInProgress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 1 || len(operations[0].Done) != 1 || len(operations[0].Pending) != 1 {
+		t.Fatalf("operations in progress = %+v, want one with a group done and a group pending", operations)
+	}
+	var id = operations[0].Key.ID
+	if len(reports) != 1 || !strings.Contains(reports[0], fmt.Sprintf("/twinlunch-resume-pair %d", id)) {
+		t.Errorf("report = %q, want it to tell how to resume operation %d", reports, id)
+	}
+	if stored := storedTwinLunches(t, fd); len(stored) != 1 {
+		t.Errorf("stored twin lunches = %q, want the first group only", stored)
+	}
+
+	handleResumePairCommand(slack.SlashCommand{Command: "/twinlunch-resume-pair", UserID: "UADMIN", Text: fmt.Sprint(id)})
+
+	var paired []string
+	for _, members := range storedTwinLunches(t, fd) {
+		paired = append(paired, members...)
+	}
+	sort.Strings(paired)
+	if !reflect.DeepEqual(paired, []string{"U1", "U2", "U3", "U4"}) {
+		t.Errorf("paired users after resuming = %q, want the users of both groups", paired)
+	}
+	if operation := storedOperation(t, fd, id); !operation.Completed || len(operation.Pending) != 0 || len(operation.Done) != 2 {
+		t.Errorf("operation after resuming = %+v, want it completed", operation)
+	}
+
+	handleResumePairCommand(slack.SlashCommand{Command: "/twinlunch-resume-pair", UserID: "UADMIN", Text: fmt.Sprint(id)})
+	deliveries.Wait()
+
+	var messages = fs.messagesTo(dmChannel("UADMIN"))
+	if len(messages) == 0 || !strings.Contains(messages[len(messages)-1], messageTo("UADMIN", "operationCompleted", messageData{"ID": id})) {
+		t.Errorf("messages = %q, want the operation to be completed", messages)
+	}
+}
+
+func TestResumeInterruptedPairing(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	// the bot stopped after creating the first group, before saving the progress of the operation
+	var operation = &TwinLunchOperation{Key: datastore.IDKey("TwinLunchOperation", 42, nil), Admin: "UADMIN", CreatedAt: time.Now(), Pending: []string{"U1,U2", "U3,U4"}}
+	if err := saveOperation(operation); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+		t.Fatal(err)
+	}
+
+	handleResumePairCommand(slack.SlashCommand{Command: "/twinlunch-resume-pair", UserID: "UADMIN", Text: "42"})
+
+	if stored := sortedMembers(storedTwinLunches(t, fd)); !reflect.DeepEqual(stored, []string{"U1,U2", "U3,U4"}) {
+		t.Errorf("stored twin lunches = %q, want the already paired group skipped", stored)
+	}
+	if operation := storedOperation(t, fd, 42); !operation.Completed || !reflect.DeepEqual(operation.Done, []string{"U1,U2", "U3,U4"}) {
+		t.Errorf("operation = %+v, want it completed", operation)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	messageLinkRegexp = regexp.MustCompile(`/archives/([A-Z0-9]+)/p(\d{10})(\d{6})`)
+
+	// dryRunRegexp matches a --dry-run flag anywhere, or a preview keyword at the end of the command text
+	dryRunRegexp = regexp.MustCompile(`(?:^|[ \t])--dry-run(?:[ \t]|$)|(?:^|\s)preview\s*$`)
+
+	// dryRunCommands are the commands which only report what they would do when given a dry run flag
+	dryRunCommands = map[string]struct{}{
+		"/twinlunch-add":    {},
+		"/twinlunch-import": {},
+		"/twinlunch-random": {},
+	}
+
+	// groupSize is the number of users put together by automatic pairing, and the maximum given to /twinlunch-add
+	groupSize = 2
+)
+
+// parseDryRun removes the dry run flag from the command text, the lines of the text are kept.
+func parseDryRun(text string) (string, bool) {
+	if !dryRunRegexp.MatchString(text) {
+		return text, false
+	}
+	return dryRunRegexp.ReplaceAllString(text, " "), true
+}
+
+// skippedUser is a user who can't be automatically paired, the reason is a message rendered in the language of each admin.
+type skippedUser struct {
+	user   string
+	reason string
+	data   messageData
+}
+
+// isDryRun tells if command is a dry run, other commands may contain the flag in their text.
+func isDryRun(command slack.SlashCommand) bool {
+	if _, ok := dryRunCommands[command.Command]; !ok {
+		return false
+	}
+	var _, dryRun = parseDryRun(command.Text)
+	return dryRun
+}
+
+// autoPairingSkipReason tells why a user can't be automatically paired, or returns nil.
+// inactive are the users whose Slack account can't be paired, looked up beforehand for all the users at once.
+func autoPairingSkipReason(user string, inactive map[string]struct{}) (*skippedUser, error) {
+	if _, ok := twinLunches.Get(user); ok {
+		return &skippedUser{user, "skipPaired", nil}, nil
+	}
+
+	if _, ok := inactive[user]; ok {
+		return &skippedUser{user, "skipInactive", nil}, nil
+	}
+
+	if inCooldown(user) {
+		return &skippedUser{user, "skipCooldown", nil}, nil
+	}
+
+	if maxTwinLunchesPerUser > 0 {
+		var count, err = countProgramTwinLunches(user)
+		if err != nil {
+			return nil, err
+		}
+		if isCapped(count) {
+			return &skippedUser{user, "skipCapped", messageData{"Max": maxTwinLunchesPerUser}}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// pairUsers randomly groups users by groupSize while avoiding excluded pairs, users which couldn't be grouped are returned in left.
+// Group sizes are as even as possible, and with groups of more than two a user left alone joins a group which isn't full if possible.
+func pairUsers(users []string, excluded map[string]struct{}) (groups [][]string, left []string) {
+	var remaining = append([]string(nil), users...)
+	rand.Shuffle(len(remaining), func(i, j int) {
+		remaining[i], remaining[j] = remaining[j], remaining[i]
+	})
+	// prioritized users are paired first, so that they can't be left out
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return hasPriority(remaining[i]) && !hasPriority(remaining[j])
+	})
+
+	for len(remaining) > 1 {
+		var count = (len(remaining) + groupSize - 1) / groupSize
+		var size = (len(remaining) + count - 1) / count
+
+		var group = []string{remaining[0]}
+		remaining = remaining[1:]
+
+		for i := 0; i < len(remaining) && len(group) < size; {
+			if canJoinGroup(excluded, group, remaining[i]) {
+				group = append(group, remaining[i])
+				remaining = append(remaining[:i], remaining[i+1:]...)
+			} else {
+				i++
+			}
+		}
+
+		if len(group) == 1 {
+			left = append(left, group[0])
+			continue
+		}
+
+		groups = append(groups, group)
+	}
+
+	left = append(left, remaining...)
+
+	if groupSize > 2 {
+		var alone = left
+		left = nil
+	users:
+		for _, user := range alone {
+			for i, group := range groups {
+				if len(group) < groupSize && canJoinGroup(excluded, group, user) {
+					groups[i] = append(group, user)
+					continue users
+				}
+			}
+			left = append(left, user)
+		}
+	}
+
+	return groups, left
+}
+
+func canJoinGroup(excluded map[string]struct{}, group []string, user string) bool {
+	for _, member := range group {
+		if isExcluded(excluded, member, user) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRepeat tells if two of the users were paired recently.
+func isRepeat(recent map[string]struct{}, users []string) bool {
+	for i, user := range users {
+		if !canJoinGroup(recent, users[i+1:], user) {
+			return true
+		}
+	}
+	return false
+}
+
+// planAutoPairing groups the eligible users for round, without creating the pairings.
+// recent are the pairs to avoid repeating, some groups may repeat them for lack of a better option.
+func planAutoPairing(users []string, round int) (groups [][]string, left []string, skipped []skippedUser, recent map[string]struct{}, err error) {
+	var eligible []string
+
+	inactiveList, err := inactiveUsers(users)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var inactive = make(map[string]struct{}, len(inactiveList))
+	for _, user := range inactiveList {
+		inactive[user] = struct{}{}
+	}
+
+	for _, user := range users {
+		var skip, err = autoPairingSkipReason(user, inactive)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if skip != nil {
+			skipped = append(skipped, *skip)
+			continue
+		}
+		eligible = append(eligible, user)
+	}
+
+	excluded, err := getExcludedPairs()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	recent, err = getRecentPairs(round)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var avoided = make(map[string]struct{}, len(excluded)+len(recent))
+	for key := range excluded {
+		avoided[key] = struct{}{}
+	}
+	for key := range recent {
+		avoided[key] = struct{}{}
+	}
+
+	groups, left = pairUsers(eligible, avoided)
+
+	// the users left are paired again without avoiding repeats, exclusions are never relaxed
+	if len(recent) != 0 && len(left) > 1 {
+		var relaxed [][]string
+		relaxed, left = pairUsers(left, excluded)
+		groups = append(groups, relaxed...)
+	}
+
+	return groups, left, skipped, recent, nil
+}
+
+// autoPair pairs the eligible users and reports the result, admin is empty for scheduled pairings.
+// The groups are created in a new round, unless the current round has no pairing yet.
+// The report is given as a function rendering it in the language of an admin, scheduled pairings report to all of them.
+// With dryRun the pairings are only reported. Otherwise the groups are saved in an operation first, so that the
+// pairing can be resumed with /twinlunch-resume-pair if it is interrupted.
+func autoPair(admin string, users []string, dryRun bool, report func(render func(admin string) string)) {
+	var round, err = pairingRound()
+	if err != nil {
+		logger.Println(err)
+		report(func(admin string) string { return messageTo(admin, "datastoreError", nil) })
+		return
+	}
+
+	groups, left, skipped, recent, err := planAutoPairing(users, round)
+	if err != nil {
+		logger.Println(err)
+		var id = "datastoreError"
+		if errors.Is(err, errUsersInfo) {
+			id = "userInfoError"
+		}
+		report(func(admin string) string { return messageTo(admin, id, nil) })
+		return
+	}
+
+	if dryRun {
+		report(func(admin string) string { return autoPairReport(admin, nil, groups, left, skipped, recent, true) })
+		return
+	}
+
+	if len(groups) != 0 && round != currentRound {
+		if err := setRound(round); err != nil {
+			logger.Println(err)
+			report(func(admin string) string { return messageTo(admin, "datastoreError", nil) })
+			return
+		}
+	}
+
+	operation, err := startPairingOperation(admin, groups)
+	if err != nil {
+		logger.Println(err)
+		report(func(admin string) string { return messageTo(admin, "datastoreError", nil) })
+		return
+	}
+
+	var created, alone = runPairingOperation(operation)
+
+	// the prioritized users were put first in the groups of this round
+	if len(created) != 0 {
+		consumePriorityRounds(1)
+	}
+
+	report(func(admin string) string {
+		return autoPairReport(admin, operation, created, append(left, alone...), skipped, recent, false)
+	})
+}
+
+// autoPairReport tells admin which groups were created, operation is nil for a dry run.
+func autoPairReport(admin string, operation *TwinLunchOperation, groups [][]string, left []string, skipped []skippedUser, recent map[string]struct{}, dryRun bool) string {
+	var created, repeats []pairData
+	for _, group := range groups {
+		created = append(created, newPairData(group))
+		if isRepeat(recent, group) {
+			repeats = append(repeats, newPairData(group))
+		}
+	}
+
+	// the operation is only told if some groups are left to create
+	var operationID int64
+	var pending int
+	if operation != nil && !operation.Completed {
+		operationID, pending = operation.Key.ID, len(operation.Pending)
+	}
+
+	var skippedReasons = make([]skippedData, 0, len(skipped))
+	for _, skip := range skipped {
+		skippedReasons = append(skippedReasons, skippedData{skip.user, messageTo(admin, skip.reason, skip.data)})
+	}
+
+	return messageTo(admin, "autoPairReport", messageData{
+		"Created":      created,
+		"Left":         left,
+		"Skipped":      skippedReasons,
+		"Repeats":      repeats,
+		"RepeatRounds": repeatAvoidRounds,
+		"DryRun":       dryRun,
+		"OperationID":  operationID,
+		"Pending":      pending,
+	})
+}
+
+func handleRandomCommand(command slack.SlashCommand) {
+	var text, dryRun = parseDryRun(command.Text)
+	var matches = userRegexp.FindAllStringSubmatch(text, -1)
+
+	if len(matches) < 2 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "randomUsage", nil))
+		return
+	}
+
+	var seen = make(map[string]struct{}, len(matches))
+	var users = make([]string, 0, len(matches))
+	for _, match := range matches {
+		if _, ok := seen[match[1]]; !ok {
+			seen[match[1]] = struct{}{}
+			users = append(users, match[1])
+		}
+	}
+
+	var channel, ts = sendPlaceholderToUser(command.UserID)
+
+	autoPair(command.UserID, users, dryRun, func(render func(admin string) string) {
+		replacePlaceholder(command.UserID, channel, ts, render(command.UserID))
+	})
+}
+
+func handlePairFromReactionCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+	var match []string
+	var emoji string
+
+	for _, arg := range args {
+		if m := messageLinkRegexp.FindStringSubmatch(arg); m != nil {
+			match = m
+		} else {
+			emoji = strings.Trim(arg, ":")
+		}
+	}
+
+	if len(args) != 2 || match == nil || emoji == "" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "pairFromReactionUsage", nil))
+		return
+	}
+
+	var placeholderChannel, placeholderTS = sendPlaceholderToUser(command.UserID)
+
+	var reactions, err = slackClient.GetReactions(
+		slack.NewRefToMessage(match[1], match[2]+"."+match[3]),
+		slack.GetReactionsParameters{Full: true},
+	)
+	if err != nil {
+		commandLogger(command).Printf("error getting reactions: %s", err)
+		replacePlaceholder(command.UserID, placeholderChannel, placeholderTS, messageTo(command.UserID, "reactionsError", nil))
+		return
+	}
+
+	var reacted = make(map[string]struct{})
+	var users []string
+	for _, reaction := range reactions {
+		// skin tone variants are named like "wave::skin-tone-2"
+		if reaction.Name != emoji && !strings.HasPrefix(reaction.Name, emoji+"::") {
+			continue
+		}
+		for _, user := range reaction.Users {
+			if _, ok := reacted[user]; !ok {
+				reacted[user] = struct{}{}
+				users = append(users, user)
+			}
+		}
+	}
+
+	if len(users) == 0 {
+		replacePlaceholder(command.UserID, placeholderChannel, placeholderTS, messageTo(command.UserID, "noReactions", messageData{"Emoji": emoji}))
+		return
+	}
+
+	infos, err := slackClient.GetUsersInfo(users...)
+	if err != nil {
+		commandLogger(command).Printf("error getting users info: %s", err)
+		replacePlaceholder(command.UserID, placeholderChannel, placeholderTS, messageTo(command.UserID, "reactionUsersError", nil))
+		return
+	}
+
+	var humans = make([]string, 0, len(*infos))
+	for _, info := range *infos {
+		if !info.IsBot && !info.Deleted && info.ID != "USLACKBOT" {
+			humans = append(humans, info.ID)
+		}
+	}
+
+	autoPair(command.UserID, humans, false, func(render func(admin string) string) {
+		replacePlaceholder(command.UserID, placeholderChannel, placeholderTS, render(command.UserID))
+	})
+}
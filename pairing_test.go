@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPairUsers(t *testing.T) {
+	var tests = []struct {
+		name        string
+		groupSize   int
+		users       []string
+		excluded    [][2]string
+		prioritized []string
+		groups      int
+		maxLeft     int
+	}{
+		{name: "even count", groupSize: 2, users: []string{"U1", "U2", "U3", "U4"}, groups: 2},
+		{name: "odd count", groupSize: 2, users: []string{"U1", "U2", "U3", "U4", "U5"}, groups: 2, maxLeft: 1},
+		{name: "prioritized with an odd count", groupSize: 2, users: []string{"U1", "U2", "U3", "U4", "U5"}, prioritized: []string{"U5"}, groups: 2, maxLeft: 1},
+		{name: "excluded pair", groupSize: 2, users: []string{"U1", "U2"}, excluded: [][2]string{{"U1", "U2"}}, maxLeft: 2},
+		{name: "exclusion with another option", groupSize: 2, users: []string{"U1", "U2", "U3"}, excluded: [][2]string{{"U1", "U2"}}, groups: 1, maxLeft: 1},
+		{name: "groups of three", groupSize: 3, users: []string{"U1", "U2", "U3", "U4", "U5", "U6", "U7"}, groups: 3},
+		{name: "even groups of three", groupSize: 3, users: []string{"U1", "U2", "U3", "U4"}, groups: 2},
+		// when U1, U2 and U3 are grouped first, U4 and U5 are left alone and the group is already full
+		{name: "users left alone don't overfill a group", groupSize: 3, users: []string{"U1", "U2", "U3", "U4", "U5"}, excluded: [][2]string{{"U4", "U5"}}, maxLeft: 2},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			setupFakes(t)
+
+			var savedGroupSize = groupSize
+			groupSize = test.groupSize
+			t.Cleanup(func() { groupSize = savedGroupSize })
+
+			var excluded = make(map[string]struct{})
+			for _, pair := range test.excluded {
+				excluded[exclusionKey(pair[0], pair[1]).Name] = struct{}{}
+			}
+			for _, user := range test.prioritized {
+				if err := updateTwinLunchUser(user, func(twinLunchUser *TwinLunchUser) { twinLunchUser.PriorityRounds = 1 }); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the users are shuffled, each run may group them differently
+			for run := 0; run < 100; run++ {
+				var groups, left = pairUsers(test.users, excluded)
+
+				var seen []string
+				for _, group := range groups {
+					if len(group) < 2 || len(group) > test.groupSize {
+						t.Fatalf("group %q has %d members, want 2 to %d", group, len(group), test.groupSize)
+					}
+					if isRepeat(excluded, group) {
+						t.Fatalf("group %q has an excluded pair", group)
+					}
+					seen = append(seen, group...)
+				}
+				seen = append(seen, left...)
+				sort.Strings(seen)
+				if strings.Join(seen, ",") != strings.Join(test.users, ",") {
+					t.Fatalf("grouped and left users = %q, want each of %q once", seen, test.users)
+				}
+
+				if test.groups != 0 && len(groups) != test.groups {
+					t.Fatalf("groups = %q, want %d groups", groups, test.groups)
+				}
+				if len(left) > test.maxLeft {
+					t.Fatalf("left = %q, want at most %d", left, test.maxLeft)
+				}
+				for _, user := range test.prioritized {
+					if containsUser(left, user) {
+						t.Fatalf("prioritized user %s is left", user)
+					}
+				}
+			}
+		})
+	}
+}
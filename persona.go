@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	defaultPersonaEmoji = "question"
+
+	minCodenameLength = 2
+	maxCodenameLength = 30
+)
+
+var (
+	emojiRegexp = regexp.MustCompile(`^[a-z0-9_+'-]+$`)
+
+	personaEmojis       []string
+	personaEmojiPerPair bool
+
+	reservedCodenames = []string{"Twin Lunch Bot", "Slackbot"}
+
+	// aliases are stored by emoji, so that entries can be added or reordered without changing the existing ones
+	aliases = []alias{
+		{"fox_face", "aliasFox"},
+		{"owl", "aliasOwl"},
+		{"panda_face", "aliasPanda"},
+		{"penguin", "aliasPenguin"},
+		{"koala", "aliasKoala"},
+		{"tiger", "aliasTiger"},
+		{"octopus", "aliasOctopus"},
+		{"hedgehog", "aliasHedgehog"},
+		{"otter", "aliasOtter"},
+		{"turtle", "aliasTurtle"},
+		{"frog", "aliasFrog"},
+		{"wolf", "aliasWolf"},
+	}
+)
+
+// alias is the default name and avatar of a member of a pairing, name is a message ID.
+type alias struct {
+	emoji, name string
+}
+
+func pickPairPersonaEmoji() string {
+	if !personaEmojiPerPair || len(personaEmojis) == 0 {
+		return ""
+	}
+	return personaEmojis[rand.Intn(len(personaEmojis))]
+}
+
+// personaEmoji returns the avatar of the messages relayed from user, the configured persona emojis take precedence over the aliases.
+func personaEmoji(twinLunch *TwinLunch, user string) string {
+	if len(personaEmojis) == 0 {
+		if alias := twinLunch.alias(user); alias != nil {
+			return alias.emoji
+		}
+		return defaultPersonaEmoji
+	}
+	if personaEmojiPerPair {
+		if twinLunch.Emoji == "" {
+			return defaultPersonaEmoji
+		}
+		return twinLunch.Emoji
+	}
+	return personaEmojis[rand.Intn(len(personaEmojis))]
+}
+
+func (twinLunch *TwinLunch) Codename(user string) string {
+	var codename string
+	switch i := twinLunch.memberIndex(user); {
+	case i == 0:
+		codename = twinLunch.Codename1
+	case i == 1:
+		codename = twinLunch.Codename2
+	case i > 1 && i-2 < len(twinLunch.OthersCodenames):
+		codename = twinLunch.OthersCodenames[i-2]
+	}
+	if codename != "" {
+		return codename
+	}
+	if alias := twinLunch.alias(user); alias != nil {
+		return message(alias.name, nil)
+	}
+	return message("defaultCodename", nil)
+}
+
+// assignAliases picks a different alias for each member.
+// The pick is seeded with the creation time, which the members don't know, so the same user gets unrelated aliases in different pairings.
+func (twinLunch *TwinLunch) assignAliases() {
+	var members = twinLunch.Members()
+	var taken = make([]bool, len(aliases))
+
+	twinLunch.Aliases = make([]string, len(members))
+	for i, user := range members {
+		var h = fnv.New32a()
+		fmt.Fprintf(h, "%d/%s", twinLunch.CreatedAt.UnixNano(), user)
+
+		var j = int(h.Sum32() % uint32(len(aliases)))
+		for n := 0; taken[j] && n < len(aliases); n++ {
+			j = (j + 1) % len(aliases)
+		}
+		taken[j] = true
+
+		twinLunch.Aliases[i] = aliases[j].emoji
+	}
+}
+
+func (twinLunch *TwinLunch) alias(user string) *alias {
+	var i = twinLunch.memberIndex(user)
+	if i == -1 || i >= len(twinLunch.Aliases) {
+		return nil
+	}
+	for j := range aliases {
+		if aliases[j].emoji == twinLunch.Aliases[i] {
+			return &aliases[j]
+		}
+	}
+	return nil
+}
+
+func (twinLunch *TwinLunch) setCodename(user string, codename string) {
+	switch i := twinLunch.memberIndex(user); {
+	case i == 0:
+		twinLunch.Codename1 = codename
+	case i == 1:
+		twinLunch.Codename2 = codename
+	case i > 1:
+		// the slice is copied, so that a pairing copy being updated doesn't share it
+		var codenames = make([]string, len(twinLunch.Others))
+		copy(codenames, twinLunch.OthersCodenames)
+		codenames[i-2] = codename
+		twinLunch.OthersCodenames = codenames
+	}
+}
+
+// partnersCodenames returns the codenames of the partners of user, separated by commas.
+func (twinLunch *TwinLunch) partnersCodenames(user string) string {
+	var codenames []string
+	for _, partner := range twinLunch.Partners(user) {
+		codenames = append(codenames, twinLunch.Codename(partner))
+	}
+	return strings.Join(codenames, ", ")
+}
+
+func handleCodenameCommand(command slack.SlashCommand) {
+	var user = command.UserID
+	var text = command.Text
+
+	if _, ok := twinLunchAdmins[command.UserID]; ok {
+		if match := userRegexp.FindStringSubmatch(text); match != nil {
+			user = match[1]
+			text = userRegexp.ReplaceAllString(text, "")
+		}
+	}
+
+	var codename = strings.Join(strings.Fields(text), " ")
+
+	if length := utf8.RuneCountInString(codename); length < minCodenameLength || length > maxCodenameLength {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "codenameLength", messageData{"Min": minCodenameLength, "Max": maxCodenameLength}))
+		return
+	}
+
+	if strings.ContainsAny(codename, "<>@") {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "codenameInvalidChars", nil))
+		return
+	}
+
+	for _, reserved := range append(reservedCodenames, message("defaultCodename", nil)) {
+		if strings.EqualFold(codename, reserved) {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "codenameReserved", messageData{"Codename": codename}))
+			return
+		}
+	}
+
+	var twinLunch, ok = twinLunches.Get(user)
+	if !ok {
+		if user == command.UserID {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "noTwinLunch", nil))
+		} else {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userHasNoTwinLunch", messageData{"User": user}))
+		}
+		return
+	}
+
+	for _, partner := range twinLunch.Partners(user) {
+		if strings.EqualFold(codename, twinLunch.Codename(partner)) {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "codenameTaken", messageData{"Codename": codename}))
+			return
+		}
+	}
+
+	var updated = *twinLunch
+	updated.setCodename(user, codename)
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, updated.Key, &updated)
+		return err
+	}); err != nil {
+		commandLogger(command).Printf("error writing key in datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	// the deliveries of relayed messages may still read the previous pairing, so it is replaced rather than modified
+	twinLunches.Pair(&updated)
+
+	if user == command.UserID {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "codenameSet", messageData{"Codename": codename}))
+	} else {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "codenameSetFor", messageData{"User": user, "Codename": codename}))
+	}
+}
+
+func handlePreviewAsCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "previewUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+	var data = messageData{
+		"User":            user,
+		"Paired":          false,
+		"Intro":           "",
+		"PartnerCodename": "",
+		"Emojis":          "",
+		"Codename":        "",
+		"NoTwinLunch":     messageTo(user, "noTwinLunch", nil),
+	}
+
+	if twinLunch, ok := twinLunches.Get(user); ok {
+		var partnersEmojis []string
+		for _, partner := range twinLunch.Partners(user) {
+			partnersEmojis = append(partnersEmojis, personaEmoji(twinLunch, partner))
+		}
+		var emojis = ":" + strings.Join(partnersEmojis, ": :") + ":"
+		if len(personaEmojis) != 0 && !personaEmojiPerPair {
+			emojis = ":" + strings.Join(personaEmojis, ": :") + ":"
+		}
+
+		data["Paired"] = true
+		data["Intro"] = introMessage(twinLunch, user)
+		data["PartnerCodename"] = twinLunch.partnersCodenames(user)
+		data["Emojis"] = emojis
+		data["Codename"] = twinLunch.Codename(user)
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "preview", data))
+}
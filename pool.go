@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+var twinLunchPoolKey = datastore.NameKey("TwinLunchPool", "default", nil)
+
+// TwinLunchCandidate is a user who signed up for automatic pairing, keyed by user ID.
+type TwinLunchCandidate struct {
+	JoinedAt time.Time
+}
+
+func twinLunchCandidateKey(user string) *datastore.Key {
+	return datastore.NameKey("TwinLunchCandidate", user, twinLunchPoolKey)
+}
+
+func handleJoinCommand(command slack.SlashCommand) {
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, twinLunchCandidateKey(command.UserID), &TwinLunchCandidate{time.Now()})
+		return err
+	}); err != nil {
+		commandLogger(command).Printf("error writing candidate in datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	updateHome(command.UserID)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "joined", nil))
+}
+
+func handleLeaveCommand(command slack.SlashCommand) {
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Delete(ctx, twinLunchCandidateKey(command.UserID))
+	}); err != nil {
+		commandLogger(command).Printf("error deleting candidate in datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	updateHome(command.UserID)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "left", nil))
+}
+
+func getPoolCandidates() ([]string, error) {
+	var keys []*datastore.Key
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var err error
+		keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchCandidate").Ancestor(twinLunchPoolKey).KeysOnly(), nil)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	var users = make([]string, 0, len(keys))
+	for _, key := range keys {
+		users = append(users, key.Name)
+	}
+	return users, nil
+}
+
+// handlePairPoolCommand pairs the users who signed up with /twinlunch-join, they stay in the pool for the next rounds.
+func handlePairPoolCommand(command slack.SlashCommand) {
+	var users, err = getPoolCandidates()
+	if err != nil {
+		commandLogger(command).Printf("error reading candidates from datastore: %s", err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	if len(users) == 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "poolEmpty", nil))
+		return
+	}
+
+	var channel, ts = sendPlaceholderToUser(command.UserID)
+
+	autoPair(command.UserID, users, false, func(render func(admin string) string) {
+		replacePlaceholder(command.UserID, channel, ts, render(command.UserID))
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+func hasPriority(user string) bool {
+	return getTwinLunchUser(user).PriorityRounds > 0
+}
+
+// consumePriorityRounds decrements the priority of all users when the round advances, or when an automatic pairing creates groups.
+func consumePriorityRounds(elapsed int) {
+	var users []string
+
+	twinLunchUsersMu.Lock()
+	for user, twinLunchUser := range twinLunchUsers {
+		if twinLunchUser.PriorityRounds > 0 {
+			users = append(users, user)
+		}
+	}
+	twinLunchUsersMu.Unlock()
+
+	for _, user := range users {
+		if err := updateTwinLunchUser(user, func(twinLunchUser *TwinLunchUser) {
+			if twinLunchUser.PriorityRounds -= elapsed; twinLunchUser.PriorityRounds < 0 {
+				twinLunchUser.PriorityRounds = 0
+			}
+		}); err != nil {
+			logger.Println(err)
+		}
+	}
+}
+
+func handlePrioritizeCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(args) != 2 || len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "prioritizeUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+
+	var rounds, err = strconv.Atoi(args[1])
+	if err != nil || rounds < 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "prioritizeUsage", nil))
+		return
+	}
+
+	if err := updateTwinLunchUser(user, func(twinLunchUser *TwinLunchUser) {
+		twinLunchUser.PriorityRounds = rounds
+	}); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "prioritized", messageData{"User": user, "Rounds": rounds}))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+
+	"github.com/slack-go/slack"
+)
+
+// handleQuitCommand lets a user end their own twin lunch, the partners aren't told who ended it.
+func handleQuitCommand(command slack.SlashCommand) {
+	var twinLunch, ok = twinLunches.Get(command.UserID)
+	if !ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "quitNoTwinLunch", nil))
+		return
+	}
+
+	// the other members of a group stay together
+	if len(twinLunch.Others) != 0 {
+		quitGroup(command, twinLunch)
+		return
+	}
+
+	if err := deleteTwinLunch(twinLunch); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	twinLunches.Unpair(twinLunch)
+	updateTopic()
+
+	recordAudit(auditActionPairQuit, command.UserID, twinLunch.Members()...)
+
+	onTwinLunchEnded(twinLunch, command.UserID, endQuit)
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "quit", nil))
+}
+
+// quitGroup removes the member who quits from their group, the others aren't told who left either.
+func quitGroup(command slack.SlashCommand, twinLunch *TwinLunch) {
+	var user = command.UserID
+	var updated = twinLunch.withoutMember(user)
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, updated.Key, updated)
+		return err
+	}); err != nil {
+		commandLogger(command).Printf("error writing key in datastore: %s", err)
+		sendBotMessageToUser(user, messageTo(user, "datastoreError", nil))
+		return
+	}
+
+	// the deliveries of relayed messages may still read the previous pairing, so it is replaced rather than modified
+	twinLunches.Replace(twinLunch, updated)
+
+	recordAudit(auditActionPairQuit, user, twinLunch.Members()...)
+
+	dropDeliveries(user)
+	if pinIntro {
+		go unpinIntro(user)
+	}
+	startCooldown(user)
+
+	for _, member := range updated.Members() {
+		sendNotificationToUser(member, essentialMessage, messageTo(member, "memberQuit", nil))
+	}
+
+	updateHome(twinLunch.Members()...)
+
+	sendBotMessageToUser(user, messageTo(user, "quit", nil))
+}
+
+// withoutMember returns a copy of the group without user, the other members keep their codename, alias and disclaimer flag.
+func (twinLunch *TwinLunch) withoutMember(user string) *TwinLunch {
+	var members = twinLunch.Members()
+	var removed = twinLunch.memberIndex(user)
+
+	// the codenames and flags of the others may be missing, in older pairings
+	var codenames = make([]string, len(members))
+	var disclaimed = make([]bool, len(members))
+	codenames[0], codenames[1] = twinLunch.Codename1, twinLunch.Codename2
+	disclaimed[0], disclaimed[1] = twinLunch.Disclaimed1, twinLunch.Disclaimed2
+	copy(codenames[2:], twinLunch.OthersCodenames)
+	copy(disclaimed[2:], twinLunch.OthersDisclaimed)
+
+	var kept []int
+	for i := range members {
+		if i != removed {
+			kept = append(kept, i)
+		}
+	}
+
+	var updated = *twinLunch
+	updated.Others, updated.OthersCodenames, updated.OthersDisclaimed, updated.Aliases = nil, nil, nil, nil
+
+	updated.User1, updated.Codename1, updated.Disclaimed1 = members[kept[0]], codenames[kept[0]], disclaimed[kept[0]]
+	updated.User2, updated.Codename2, updated.Disclaimed2 = members[kept[1]], codenames[kept[1]], disclaimed[kept[1]]
+	for _, i := range kept[2:] {
+		updated.Others = append(updated.Others, members[i])
+		updated.OthersCodenames = append(updated.OthersCodenames, codenames[i])
+		updated.OthersDisclaimed = append(updated.OthersDisclaimed, disclaimed[i])
+	}
+
+	if len(twinLunch.Aliases) == len(members) {
+		for _, i := range kept {
+			updated.Aliases = append(updated.Aliases, twinLunch.Aliases[i])
+		}
+	}
+
+	return &updated
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestHandleQuitCommand(t *testing.T) {
+	var fs, fd = setupFakes(t)
+
+	var events = make(chan WorkflowEvent, 2)
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event WorkflowEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events <- event
+	}))
+	defer server.Close()
+
+	var savedURL = workflowWebhookURL
+	workflowWebhookURL = server.URL
+	t.Cleanup(func() { workflowWebhookURL = savedURL })
+
+	var twinLunch, err = createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Wait()
+
+	handleQuitCommand(slack.SlashCommand{Command: "/twinlunch-quit", UserID: "U1"})
+	deliveries.Wait()
+
+	var intro = introMessage(twinLunch, "U1")
+	checkMessages(t, fs, map[string][]string{
+		dmChannel("U1"): {intro, message("quit", nil)},
+		dmChannel("U2"): {intro, message("ended", messageData{"Round": twinLunch.Round})},
+	})
+	if stored := storedTwinLunches(t, fd); len(stored) != 0 {
+		t.Errorf("stored twin lunches = %q, want none", stored)
+	}
+
+	// the events may be posted in any order
+	var admins = make(map[string]string)
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			admins[event.Event] = event.Admin
+		case <-time.After(5 * time.Second):
+			t.Fatal("missing workflow event")
+		}
+	}
+	if admin, ok := admins[workflowEventTwinLunchRemoved]; !ok || admin != "" {
+		t.Errorf("admins of the workflow events = %q, want no admin for the quit", admins)
+	}
+}
+
+func TestQuitGroup(t *testing.T) {
+	var fs, fd = setupFakes(t)
+
+	var twinLunch, err = createTwinLunch([]string{"U1", "U2", "U3"}, 0, "UADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	twinLunch.setCodename("U3", "Fox")
+	deliveries.Wait()
+
+	handleQuitCommand(slack.SlashCommand{Command: "/twinlunch-quit", UserID: "U1"})
+	deliveries.Wait()
+
+	if _, ok := twinLunches.Get("U1"); ok {
+		t.Error("U1 is still paired after quitting")
+	}
+	var remaining, ok = twinLunches.Get("U2")
+	if !ok || !reflect.DeepEqual(remaining.Members(), []string{"U2", "U3"}) {
+		t.Fatalf("pairing of U2 = %+v, want U2 and U3 together", remaining)
+	}
+	if codename := remaining.Codename("U3"); codename != "Fox" {
+		t.Errorf("codename of U3 = %q, want it kept", codename)
+	}
+	if stored := sortedMembers(storedTwinLunches(t, fd)); !reflect.DeepEqual(stored, []string{"U2,U3"}) {
+		t.Errorf("stored twin lunches = %q, want U2 and U3", stored)
+	}
+
+	var intro = introMessage(twinLunch, "U1")
+	checkMessages(t, fs, map[string][]string{
+		dmChannel("U1"): {intro, message("quit", nil)},
+		dmChannel("U2"): {introMessage(twinLunch, "U2"), message("memberQuit", nil)},
+		dmChannel("U3"): {introMessage(twinLunch, "U3"), message("memberQuit", nil)},
+	})
+
+	// a group of two is dissolved
+	handleQuitCommand(slack.SlashCommand{Command: "/twinlunch-quit", UserID: "U2"})
+	deliveries.Wait()
+
+	if _, ok := twinLunches.Get("U3"); ok {
+		t.Error("U3 is still paired after the last partner quit")
+	}
+	if stored := storedTwinLunches(t, fd); len(stored) != 0 {
+		t.Errorf("stored twin lunches = %q, want none", stored)
+	}
+	checkMessages(t, fs, map[string][]string{
+		dmChannel("U3"): {introMessage(twinLunch, "U3"), message("memberQuit", nil), message("ended", messageData{"Round": twinLunch.Round})},
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+)
+
+var (
+	// relayRateLimit is the number of messages per minute a user can relay once their burst is used, zero disables the limit
+	relayRateLimit = 30
+	relayRateBurst = 20
+
+	// relayBuckets is only accessed from the run loop
+	relayBuckets = make(map[string]*relayBucket)
+)
+
+// relayBucket is a token bucket, a relayed message takes a token and tokens come back at relayRateLimit per minute.
+type relayBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// notified is set once the user has been told they're throttled, until they can relay again
+	notified bool
+}
+
+// allowRelay tells if user can relay a message now, user is told once when they exceed the limit.
+func allowRelay(user string) bool {
+	if relayRateLimit <= 0 {
+		return true
+	}
+
+	var now = time.Now()
+
+	var bucket, ok = relayBuckets[user]
+	if !ok {
+		bucket = &relayBucket{tokens: float64(relayRateBurst), updatedAt: now}
+		relayBuckets[user] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.updatedAt).Minutes() * float64(relayRateLimit)
+	if bucket.tokens > float64(relayRateBurst) {
+		bucket.tokens = float64(relayRateBurst)
+	}
+	bucket.updatedAt = now
+
+	if bucket.tokens < 1 {
+		if !bucket.notified {
+			bucket.notified = true
+			logger.Printf("throttling messages of user %s", user)
+			sendBotMessageToUser(user, messageTo(user, "relayThrottled", nil))
+		}
+		return false
+	}
+
+	bucket.tokens--
+	bucket.notified = false
+
+	return true
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	// unreachableNotified holds the "sender/recipient" pairs for which the sender was told the recipient is unreachable
+	unreachableNotifiedMu sync.Mutex
+	unreachableNotified   = make(map[string]struct{})
+
+	errUsersInfo = errors.New("error getting users info")
+)
+
+// inactiveUser returns the first of users whose account is deactivated, isn't a human or doesn't exist, or an empty string.
+func inactiveUser(users []string) (string, error) {
+	var inactive, err = inactiveUsers(users)
+	if err != nil || len(inactive) == 0 {
+		return "", err
+	}
+	return inactive[0], nil
+}
+
+// inactiveUsers returns the users whose account is deactivated, isn't a human or doesn't exist, in the order of users.
+// Slack fails the whole lookup if one of the accounts doesn't exist, the users are then looked up one by one to tell which.
+func inactiveUsers(users []string) ([]string, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	var infos, err = slackClient.GetUsersInfo(users...)
+	if err != nil {
+		var slackErr slack.SlackErrorResponse
+		if !errors.As(err, &slackErr) || slackErr.Err != "user_not_found" {
+			return nil, fmt.Errorf("%w: %s", errUsersInfo, err)
+		}
+
+		if len(users) == 1 {
+			return users, nil
+		}
+
+		var inactive []string
+		for _, user := range users {
+			var userInactive, err = inactiveUsers([]string{user})
+			if err != nil {
+				return nil, err
+			}
+			inactive = append(inactive, userInactive...)
+		}
+		return inactive, nil
+	}
+
+	var active = make(map[string]struct{}, len(*infos))
+	for _, info := range *infos {
+		rememberSlackLocale(info.ID, info.Locale)
+		if !info.IsBot && !info.Deleted && info.ID != "USLACKBOT" {
+			active[info.ID] = struct{}{}
+		}
+	}
+
+	var inactive []string
+	for _, user := range users {
+		if _, ok := active[user]; !ok {
+			inactive = append(inactive, user)
+		}
+	}
+
+	return inactive, nil
+}
+
+// isUnreachableUserError tells if a message can't be delivered because of the recipient's account, retrying won't help.
+func isUnreachableUserError(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return false
+	}
+
+	switch slackErr.Err {
+	case "user_disabled", "user_not_found", "user_not_visible", "account_inactive", "is_archived", "cannot_dm_bot":
+		return true
+	}
+
+	return false
+}
+
+// notifyUnreachablePartner tells sender once that their messages can't be delivered to recipient anymore.
+func notifyUnreachablePartner(sender string, recipient string) {
+	unreachableNotifiedMu.Lock()
+	var key = sender + "/" + recipient
+	var _, notified = unreachableNotified[key]
+	unreachableNotified[key] = struct{}{}
+	unreachableNotifiedMu.Unlock()
+
+	if notified {
+		return
+	}
+
+	logger.Printf("warning: user %s is unreachable", recipient)
+
+	sendBotMessageToUser(sender, messageTo(sender, "partnerUnreachable", nil))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestInactiveUsers(t *testing.T) {
+	var tests = []struct {
+		name     string
+		users    []string
+		inactive []string
+		unknown  []string
+		want     []string
+	}{
+		{name: "all active", users: []string{"U1", "U2"}},
+		{name: "deactivated", users: []string{"U1", "U2", "U3"}, inactive: []string{"U3"}, want: []string{"U3"}},
+		{name: "unknown", users: []string{"U1", "U2", "U3"}, unknown: []string{"U2"}, want: []string{"U2"}},
+		{name: "unknown and deactivated", users: []string{"U1", "U2", "U3"}, inactive: []string{"U1"}, unknown: []string{"U3"}, want: []string{"U1", "U3"}},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var fs, _ = setupFakes(t)
+			for _, user := range test.inactive {
+				fs.inactive[user] = true
+			}
+			for _, user := range test.unknown {
+				fs.unknown[user] = true
+			}
+
+			var inactive, err = inactiveUsers(test.users)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(inactive, test.want) {
+				t.Errorf("inactive users = %q, want %q", inactive, test.want)
+			}
+		})
+	}
+}
+
+func TestAutoPairSkipsInactiveUsers(t *testing.T) {
+	var fs, fd = setupFakes(t)
+	fs.inactive["U3"] = true
+	fs.unknown["U4"] = true
+
+	var reports []string
+	autoPair("UADMIN", []string{"U1", "U2", "U3", "U4"}, false, func(render func(admin string) string) { reports = append(reports, render("UADMIN")) })
+	deliveries.Wait()
+
+	if stored := sortedMembers(storedTwinLunches(t, fd)); !reflect.DeepEqual(stored, []string{"U1,U2"}) {
+		t.Errorf("stored twin lunches = %q, want only the active users paired", stored)
+	}
+	for _, user := range []string{"U3", "U4"} {
+		if want := "<@" + user + "> " + message("skipInactive", nil); len(reports) != 1 || !strings.Contains(reports[0], want) {
+			t.Errorf("report = %q, want it to contain %q", reports, want)
+		}
+	}
+}
+
+func TestImportRejectsInactiveUsers(t *testing.T) {
+	var fs, fd = setupFakes(t)
+	twinLunchAdmins["UADMIN"] = struct{}{}
+	fs.inactive["U2"] = true
+	fs.unknown["U4"] = true
+
+	handleCommand(slack.SlashCommand{Command: "/twinlunch-import", UserID: "UADMIN", Text: "<@U1> <@U2>\n<@U3> <@U4>\n<@U5> <@U6>"})
+	deliveries.Wait()
+
+	checkMessages(t, fs, map[string][]string{dmChannel("UADMIN"): {message("importInvalid", messageData{"Errors": []string{
+		message("importLineError", messageData{"Line": 1, "Error": message("userInactive", messageData{"User": "U2"})}),
+		message("importLineError", messageData{"Line": 2, "Error": message("userInactive", messageData{"User": "U4"})}),
+	}})}})
+	if stored := storedTwinLunches(t, fd); len(stored) != 0 {
+		t.Errorf("stored twin lunches = %q, want none", stored)
+	}
+}
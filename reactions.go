@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+// TwinLunchRelayedMessage links a message to its copy in the partner's conversation, keyed by "channel/ts".
+// Each relayed message is recorded in both directions.
+type TwinLunchRelayedMessage struct {
+	TwinLunchID int64
+	Channel     string `datastore:",noindex"`
+	TS          string `datastore:",noindex"`
+	CreatedAt   time.Time
+}
+
+type reactionChange struct {
+	Added    bool
+	User     string
+	Reaction string
+	Channel  string
+	TS       string
+}
+
+func relayedMessageKey(channel string, ts string) *datastore.Key {
+	return datastore.NameKey("TwinLunchRelayedMessage", channel+"/"+ts, nil)
+}
+
+// recordRelayedMessage records the copy of a message, and the message as the copy's if both is true.
+func recordRelayedMessage(twinLunchID int64, channel string, ts string, relayedChannel string, relayedTS string, both bool) {
+	var now = time.Now()
+
+	var keys = []*datastore.Key{relayedMessageKey(relayedChannel, relayedTS)}
+	var relayed = []*TwinLunchRelayedMessage{{twinLunchID, channel, ts, now}}
+	if both {
+		keys = append(keys, relayedMessageKey(channel, ts))
+		relayed = append(relayed, &TwinLunchRelayedMessage{twinLunchID, relayedChannel, relayedTS, now})
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.PutMulti(ctx, keys, relayed)
+		return err
+	}); err != nil {
+		logger.Printf("error writing relayed message in datastore: %s", err)
+	}
+}
+
+// handleReactionChange mirrors a reaction on the partner's copy of the message, reactions on other messages are ignored.
+func handleReactionChange(change reactionChange) {
+	// relayed messages are all in direct message channels, whose IDs start with D
+	if !strings.HasPrefix(change.Channel, "D") {
+		return
+	}
+
+	var twinLunch, ok = twinLunches.Get(change.User)
+	if !ok || twinLunch.Key == nil {
+		return
+	}
+
+	var relayed TwinLunchRelayedMessage
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return datastoreClient.Get(ctx, relayedMessageKey(change.Channel, change.TS), &relayed)
+	}); err == datastore.ErrNoSuchEntity {
+		return
+	} else if err != nil {
+		logger.Printf("error reading relayed message from datastore: %s", err)
+		return
+	}
+
+	// the message may come from a previous pairing
+	if relayed.TwinLunchID != twinLunch.Key.ID {
+		return
+	}
+
+	var ref = slack.NewRefToMessage(relayed.Channel, relayed.TS)
+
+	if change.Added {
+		if err := slackClient.AddReaction(change.Reaction, ref); err != nil {
+			logger.Printf("error adding reaction: %s", err)
+		}
+	} else {
+		if err := slackClient.RemoveReaction(change.Reaction, ref); err != nil {
+			logger.Printf("error removing reaction: %s", err)
+		}
+	}
+}
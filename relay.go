@@ -0,0 +1,132 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	relayDisclaimerOff    = "off"
+	relayDisclaimerAlways = "always"
+	relayDisclaimerFirst  = "first"
+)
+
+const (
+	relayStylePlain   = "plain"
+	relayStyleQuote   = "quote"
+	relayStyleMarkers = "markers"
+)
+
+var (
+	// relayDisclaimer tells when the disclaimer footer is added to relayed messages
+	relayDisclaimer = relayDisclaimerOff
+
+	// relayStyle tells how relayed messages are wrapped, relayPrefix and relaySuffix are used by the markers style
+	relayStyle               = relayStylePlain
+	relayPrefix, relaySuffix string
+
+	markerEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	broadcastRegexp = regexp.MustCompile(`<!(channel|here|everyone)(?:\|[^>]*)?>`)
+	subteamRegexp   = regexp.MustCompile(`<!subteam\^[^|>]*(?:\|@?([^>]*))?>`)
+	// specialRegexp matches the remaining special tokens, such as dates, whose label is their fallback text
+	specialRegexp     = regexp.MustCompile(`<!(?:[^|>]*)(?:\|([^>]*))?>`)
+	userMentionRegexp = regexp.MustCompile(`<@[UW][A-Z0-9]*(?:\|[^>]*)?>`)
+)
+
+// neutralizeBroadcasts turns broadcast and user group mentions into inert text,
+// so that a relayed message can't notify anyone besides the partner, user is the recipient.
+func neutralizeBroadcasts(user string, text string) string {
+	text = broadcastRegexp.ReplaceAllString(text, "@$1")
+
+	return subteamRegexp.ReplaceAllStringFunc(text, func(token string) string {
+		var name = subteamRegexp.FindStringSubmatch(token)[1]
+		if name == "" {
+			name = messageTo(user, "mentionedGroup", nil)
+		}
+		return "@" + name
+	})
+}
+
+// neutralizeMentions turns the mentions of a relayed message into inert text, besides broadcasts.
+// User mentions are replaced without their name, so that the partner can't learn who is mentioned, the sender included.
+// user is the recipient, whose language is used for the replacements.
+func neutralizeMentions(user string, text string) string {
+	text = neutralizeBroadcasts(user, text)
+	text = specialRegexp.ReplaceAllString(text, "$1")
+
+	return userMentionRegexp.ReplaceAllLiteralString(text, "@"+messageTo(user, "mentionedUser", nil))
+}
+
+// wrapRelayedText applies the relay style to a relayed message.
+// Markers are escaped and put on their own lines, so that they can't interfere with the message formatting.
+func wrapRelayedText(text string) string {
+	switch relayStyle {
+	case relayStyleQuote:
+		var lines = strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = "> " + line
+		}
+		return strings.Join(lines, "\n")
+
+	case relayStyleMarkers:
+		if relayPrefix != "" {
+			text = markerEscaper.Replace(relayPrefix) + "\n" + text
+		}
+		if relaySuffix != "" {
+			text = text + "\n" + markerEscaper.Replace(relaySuffix)
+		}
+		return text
+	}
+
+	return text
+}
+
+// addRelayDisclaimer appends the disclaimer footer to a message relayed to user, if configured.
+// In first mode the footer is only added to the first message each partner receives, and twinLunch must be saved afterwards.
+func addRelayDisclaimer(twinLunch *TwinLunch, user string, text string) string {
+	if relayDisclaimer == relayDisclaimerOff {
+		return text
+	}
+
+	if relayDisclaimer == relayDisclaimerFirst {
+		if twinLunch.disclaimed(user) {
+			return text
+		}
+		twinLunch.setDisclaimed(user)
+	}
+
+	return text + relayDisclaimerFooter(user)
+}
+
+func relayDisclaimerFooter(user string) string {
+	return "\n_" + messageTo(user, "relayDisclaimer", nil) + "_"
+}
+
+func (twinLunch *TwinLunch) disclaimed(user string) bool {
+	switch i := twinLunch.memberIndex(user); {
+	case i == 0:
+		return twinLunch.Disclaimed1
+	case i == 1:
+		return twinLunch.Disclaimed2
+	case i > 1 && i-2 < len(twinLunch.OthersDisclaimed):
+		return twinLunch.OthersDisclaimed[i-2]
+	}
+	return false
+}
+
+func (twinLunch *TwinLunch) setDisclaimed(user string) {
+	switch i := twinLunch.memberIndex(user); {
+	case i == 0:
+		twinLunch.Disclaimed1 = true
+	case i == 1:
+		twinLunch.Disclaimed2 = true
+	case i > 1:
+		if len(twinLunch.OthersDisclaimed) != len(twinLunch.Others) {
+			var disclaimed = make([]bool, len(twinLunch.Others))
+			copy(disclaimed, twinLunch.OthersDisclaimed)
+			twinLunch.OthersDisclaimed = disclaimed
+		}
+		twinLunch.OthersDisclaimed[i-2] = true
+	}
+}
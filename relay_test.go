@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestNeutralizeBroadcasts(t *testing.T) {
+	var group = message("mentionedGroup", nil)
+
+	var tests = []struct {
+		text string
+		want string
+	}{
+		{"hello <!channel>", "hello @channel"},
+		{"<!here> hello", "@here hello"},
+		{"<!everyone>", "@everyone"},
+		{"<!here|here> with a label", "@here with a label"},
+		{"<!subteam^S123|@team-lunch> hi", "@team-lunch hi"},
+		{"<!subteam^S123|team-lunch>", "@team-lunch"},
+		{"<!subteam^S123>", "@" + group},
+		{"<!channel><!here>", "@channel@here"},
+		{"<@U123> and <!date^1392734382^{date}|Feb 18>", "<@U123> and <!date^1392734382^{date}|Feb 18>"},
+		{"no mention here", "no mention here"},
+	}
+
+	for _, test := range tests {
+		if text := neutralizeBroadcasts("U1", test.text); text != test.want {
+			t.Errorf("neutralizeBroadcasts(%q) = %q, want %q", test.text, text, test.want)
+		}
+	}
+}
+
+func TestRelayStyle(t *testing.T) {
+	var tests = []struct {
+		name           string
+		style          string
+		prefix, suffix string
+		text           string
+		want           string
+	}{
+		{name: "plain", style: relayStylePlain, text: "hello\n*world*", want: "hello\n*world*"},
+		{name: "quote", style: relayStyleQuote, text: "hello\n*world*", want: "> hello\n> *world*"},
+		{name: "markers", style: relayStyleMarkers, prefix: "<<< twin", suffix: "twin & co >>>", text: "hello", want: "&lt;&lt;&lt; twin\nhello\ntwin &amp; co &gt;&gt;&gt;"},
+		{name: "prefix marker only", style: relayStyleMarkers, prefix: "--", text: "hello", want: "--\nhello"},
+		{name: "mentions are neutralized before the style", style: relayStyleQuote, text: "<!here> hi", want: "> @here hi"},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var fs, _ = setupFakes(t)
+			if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+				t.Fatal(err)
+			}
+
+			var savedStyle, savedPrefix, savedSuffix = relayStyle, relayPrefix, relaySuffix
+			relayStyle, relayPrefix, relaySuffix = test.style, test.prefix, test.suffix
+			t.Cleanup(func() { relayStyle, relayPrefix, relaySuffix = savedStyle, savedPrefix, savedSuffix })
+
+			handleMessage(&slackevents.MessageEvent{Channel: dmChannel("U1"), User: "U1", ChannelType: slack.TYPE_IM, Text: test.text})
+			deliveries.Wait()
+
+			// the intro comes first
+			if relayed := fs.messagesTo(dmChannel("U2")); len(relayed) != 2 || relayed[1] != test.want {
+				t.Errorf("messages to the partner = %q, want the intro then %q", relayed, test.want)
+			}
+		})
+	}
+}
+
+func TestSimultaneousRelay(t *testing.T) {
+	var fs, _ = setupFakes(t)
+
+	var savedRateLimit = relayRateLimit
+	relayRateLimit = 0
+	t.Cleanup(func() { relayRateLimit = savedRateLimit })
+
+	if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Wait()
+
+	var messages = make(chan *slackevents.MessageEvent)
+	var done = make(chan struct{})
+	loops.Add(1)
+	go func() {
+		defer close(done)
+		run(messages, nil, nil, nil, nil)
+	}()
+
+	// both partners write at once
+	const count = 20
+	var want = make(map[string][]string)
+	for _, user := range []string{"U1", "U2"} {
+		for i := 0; i < count; i++ {
+			want[user] = append(want[user], fmt.Sprintf("message %d from %s", i, user))
+		}
+	}
+	var senders sync.WaitGroup
+	for user, texts := range want {
+		var user, texts = user, texts
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			for _, text := range texts {
+				messages <- &slackevents.MessageEvent{Channel: dmChannel(user), User: user, ChannelType: slack.TYPE_IM, Text: text}
+			}
+		}()
+	}
+	senders.Wait()
+	close(messages)
+	<-done
+	deliveries.Wait()
+
+	for user, partner := range map[string]string{"U1": "U2", "U2": "U1"} {
+		// the intro comes first
+		if relayed := fs.messagesTo(dmChannel(partner)); len(relayed) == 0 || !reflect.DeepEqual(relayed[1:], want[user]) {
+			t.Errorf("messages to %s = %q, want the intro then the messages of %s in order", partner, relayed, user)
+		}
+	}
+}
+
+func TestRelayInRecipientLanguage(t *testing.T) {
+	var fs, _ = setupFakes(t)
+
+	var savedDisclaimer = relayDisclaimer
+	relayDisclaimer = relayDisclaimerAlways
+	t.Cleanup(func() { relayDisclaimer = savedDisclaimer })
+
+	if err := updateTwinLunchUser("U2", func(twinLunchUser *TwinLunchUser) { twinLunchUser.Language = "en" }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createTwinLunch([]string{"U1", "U2", "U3"}, 0, "UADMIN"); err != nil {
+		t.Fatal(err)
+	}
+
+	handleMessage(&slackevents.MessageEvent{Channel: dmChannel("U1"), User: "U1", ChannelType: slack.TYPE_IM, Text: "hi <@U4>"})
+	deliveries.Wait()
+
+	// the intro comes first, the text of the bot is in the language of each recipient
+	for user, catalog := range map[string]map[string]string{"U2": enMessages, "U3": messageCatalogs[messageLanguage]} {
+		var want = "hi @" + catalog["mentionedUser"] + "\n_" + catalog["relayDisclaimer"] + "_"
+		if relayed := fs.messagesTo(dmChannel(user)); len(relayed) != 2 || relayed[1] != want {
+			t.Errorf("messages to %s = %q, want the intro then %q", user, relayed, want)
+		}
+	}
+}
+
+func TestForgetRelayedMessages(t *testing.T) {
+	for _, cleanup := range []bool{false, true} {
+		var cleanup = cleanup
+		t.Run(fmt.Sprintf("cleanup %t", cleanup), func(t *testing.T) {
+			var fs, fd = setupFakes(t)
+
+			var savedCleanup = cleanupOnRemove
+			cleanupOnRemove = cleanup
+			t.Cleanup(func() { cleanupOnRemove = savedCleanup })
+
+			var count = func(kind string) int {
+				t.Helper()
+				var keys, err = fd.GetAll(context.Background(), newDatastoreQuery(kind).KeysOnly(), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return len(keys)
+			}
+
+			if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+				t.Fatal(err)
+			}
+			handleMessage(&slackevents.MessageEvent{Channel: dmChannel("U1"), User: "U1", ChannelType: slack.TYPE_IM, Text: "hi", TimeStamp: "1.000001"})
+			deliveries.Wait()
+
+			if count("TwinLunchRelayedMessage") == 0 || count("TwinLunchRelayedCopy") == 0 {
+				t.Fatal("the relayed message wasn't recorded")
+			}
+
+			handleRemoveCommand(slack.SlashCommand{Command: "/twinlunch-remove", UserID: "UADMIN", Text: "<@U1> <@U2>"})
+			deliveries.Wait()
+
+			for _, kind := range []string{"TwinLunchRelayedMessage", "TwinLunchRelayedCopy"} {
+				if n := count(kind); n != 0 {
+					t.Errorf("%d %s left after the twin lunch ended, want none", n, kind)
+				}
+			}
+			if deleted := fs.callCount("DeleteMessage"); cleanup != (deleted != 0) {
+				t.Errorf("%d copies deleted in Slack with cleanup %t", deleted, cleanup)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	// reportChannel receives the reports, they are sent to all admins if it is empty
+	reportChannel  string
+	reportCooldown = time.Hour
+
+	// lastReports is only accessed from the run loop
+	lastReports = make(map[string]time.Time)
+)
+
+// handleReportCommand forwards a user report to the admins, with the pairing ID but never the partner's identity.
+func handleReportCommand(command slack.SlashCommand) {
+	var text = strings.TrimSpace(command.Text)
+
+	if text == "" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "reportUsage", nil))
+		return
+	}
+
+	if last, ok := lastReports[command.UserID]; ok && time.Since(last) < reportCooldown {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "reportTooSoon", messageData{"Until": last.Add(reportCooldown).Format(cooldownLayout)}))
+		return
+	}
+
+	// the report may be sent to a channel, so it is neutralized in the global language
+	var data = messageData{
+		"User":      command.UserID,
+		"PairingID": "",
+		"Round":     0,
+		"Since":     "",
+		"Text":      neutralizeBroadcasts("", text),
+	}
+	if twinLunch, ok := twinLunches.Get(command.UserID); ok {
+		if twinLunch.Key != nil {
+			data["PairingID"] = strconv.FormatInt(twinLunch.Key.ID, 10)
+		}
+		data["Round"] = twinLunch.Round
+		if !twinLunch.CreatedAt.IsZero() {
+			data["Since"] = twinLunch.CreatedAt.Format(cooldownLayout)
+		}
+	}
+
+	if reportChannel != "" {
+		sendBotMessageToChannel(reportChannel, message("report", data))
+	} else {
+		for admin := range twinLunchAdmins {
+			sendBotMessageToUser(admin, messageTo(admin, "report", data))
+		}
+	}
+
+	lastReports[command.UserID] = time.Now()
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "reportSent", nil))
+}
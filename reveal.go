@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// handleRevealCommand tells each user who their partner is, then clears the pairings unless keep is given.
+func handleRevealCommand(command slack.SlashCommand) {
+	var arg = strings.TrimSpace(command.Text)
+	if arg != "" && arg != "keep" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "revealUsage", nil))
+		return
+	}
+
+	var keep = arg == "keep"
+	var revealed []*TwinLunch
+
+	if keep {
+		revealed = twinLunches.List()
+	} else {
+		// clearing first, so that the reveals aren't dropped with the pending messages of the pairings
+		var err error
+		if revealed, err = clearTwinLunches(command.UserID); err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		}
+	}
+
+	for _, twinLunch := range revealed {
+		for _, user := range twinLunch.Members() {
+			var partners = twinLunch.Partners(user)
+			sendBotMessageToUser(user, messageTo(user, "reveal", messageData{"Partner": partners[0], "Others": partners[1:]}))
+		}
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "revealed", messageData{"Count": len(revealed), "Cleared": !keep}))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+var (
+	twinLunchConfigKey = datastore.NameKey("TwinLunchConfig", "default", nil)
+
+	// currentRound is only accessed from the run loop after startup
+	currentRound = 1
+)
+
+// TwinLunchConfig holds the program settings which can be changed with commands.
+type TwinLunchConfig struct {
+	Round int
+}
+
+func loadTwinLunchConfig(ctx context.Context) {
+	var config TwinLunchConfig
+
+	if err := withDatastore(ctx, func(ctx context.Context) error {
+		return datastoreClient.Get(ctx, twinLunchConfigKey, &config)
+	}); err != nil && !errors.Is(err, datastore.ErrNoSuchEntity) {
+		logger.Fatalf("error reading twin lunch config from datastore %s", err)
+	}
+
+	if config.Round > 0 {
+		currentRound = config.Round
+	}
+
+	logger.Printf("current round is %d", currentRound)
+}
+
+func handleRoundCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+
+	if len(args) == 0 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "round", messageData{"Round": currentRound}))
+		return
+	}
+
+	if len(args) != 2 || args[0] != "set" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "roundUsage", nil))
+		return
+	}
+
+	var round, err = strconv.Atoi(args[1])
+	if err != nil || round < 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "roundUsage", nil))
+		return
+	}
+
+	var previous = currentRound
+	if err := setRound(round); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	if round > previous {
+		consumePriorityRounds(round - previous)
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "roundSet", messageData{"Round": currentRound}))
+}
+
+// setRound saves round as the current round and shows it in the topic.
+func setRound(round int) error {
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, twinLunchConfigKey, &TwinLunchConfig{round})
+		return err
+	}); err != nil {
+		return fmt.Errorf("error writing twin lunch config in datastore: %w", err)
+	}
+
+	currentRound = round
+	updateTopic()
+
+	return nil
+}
+
+// pairingRound returns the round of the groups created by an automatic pairing.
+// It is the current round as long as it has no pairing, so that the first pairing and the one following
+// /twinlunch-round set don't skip a round, otherwise it is the next one.
+func pairingRound() (int, error) {
+	var keys []*datastore.Key
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var err error
+		keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchHistory").Filter("Round =", currentRound).KeysOnly().Limit(1), nil)
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("error reading history from datastore: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return currentRound, nil
+	}
+	return currentRound + 1, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestAutoPairAdvancesRound(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	var pair = func(users ...string) *TwinLunch {
+		t.Helper()
+		autoPair("UADMIN", users, false, func(render func(admin string) string) {})
+		var twinLunch, ok = twinLunches.Get(users[0])
+		if !ok {
+			t.Fatalf("%s isn't paired", users[0])
+		}
+		return twinLunch
+	}
+
+	// the first round has no pairing yet, it isn't skipped
+	if twinLunch := pair("U1", "U2"); twinLunch.Round != 1 || currentRound != 1 {
+		t.Errorf("first pairing is in round %d and current round is %d, want 1", twinLunch.Round, currentRound)
+	}
+
+	if twinLunch := pair("U3", "U4"); twinLunch.Round != 2 || currentRound != 2 {
+		t.Errorf("second pairing is in round %d and current round is %d, want 2", twinLunch.Round, currentRound)
+	}
+
+	var config TwinLunchConfig
+	if err := fd.Get(context.Background(), twinLunchConfigKey, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Round != 2 {
+		t.Errorf("saved round = %d, want 2", config.Round)
+	}
+
+	// neither a dry run nor a run without groups starts a round
+	autoPair("UADMIN", []string{"U5", "U6"}, true, func(render func(admin string) string) {})
+	autoPair("UADMIN", []string{"U1", "U2"}, false, func(render func(admin string) string) {})
+	if currentRound != 2 {
+		t.Errorf("current round = %d, want 2", currentRound)
+	}
+}
+
+func TestAutoPairConsumesPriority(t *testing.T) {
+	setupFakes(t)
+
+	if err := updateTwinLunchUser("U1", func(twinLunchUser *TwinLunchUser) { twinLunchUser.PriorityRounds = 2 }); err != nil {
+		t.Fatal(err)
+	}
+
+	autoPair("UADMIN", []string{"U1", "U2"}, true, func(render func(admin string) string) {})
+	if rounds := getTwinLunchUser("U1").PriorityRounds; rounds != 2 {
+		t.Errorf("priority rounds after a dry run = %d, want 2", rounds)
+	}
+
+	autoPair("UADMIN", []string{"U1", "U2"}, false, func(render func(admin string) string) {})
+	if rounds := getTwinLunchUser("U1").PriorityRounds; rounds != 1 {
+		t.Errorf("priority rounds after a pairing = %d, want 1", rounds)
+	}
+}
+
+func TestPlanAutoPairingAvoidsRecentPairs(t *testing.T) {
+	var tests = []struct {
+		name string
+		// started tells if the current round 5 has pairings, the plan is then for round 6
+		started bool
+		round   int
+		recent  bool
+	}{
+		{name: "before the window", started: true, round: 3},
+		{name: "first round of the window", started: true, round: 4, recent: true},
+		{name: "current round", started: true, round: 5, recent: true},
+		{name: "window of the current round", round: 3, recent: true},
+		{name: "before the window of the current round", round: 2},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var _, fd = setupFakes(t)
+
+			var savedRepeatAvoidRounds = repeatAvoidRounds
+			repeatAvoidRounds = 2
+			t.Cleanup(func() { repeatAvoidRounds = savedRepeatAvoidRounds })
+			currentRound = 5
+
+			var history = []*TwinLunchHistory{{Users: []string{"U1", "U2"}, Round: test.round}}
+			if test.started {
+				history = append(history, &TwinLunchHistory{Users: []string{"U8", "U9"}, Round: 5})
+			}
+			for i, entry := range history {
+				if _, err := fd.Put(context.Background(), datastore.IDKey("TwinLunchHistory", int64(i+1), nil), entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var round, err = pairingRound()
+			if err != nil {
+				t.Fatal(err)
+			}
+			groups, _, _, recent, err := planAutoPairing([]string{"U1", "U2"}, round)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := recent[exclusionKey("U1", "U2").Name]; ok != test.recent {
+				t.Errorf("U1 and U2 paired in round %d are recent = %t when pairing round %d, want %t", test.round, ok, round, test.recent)
+			}
+			// the repeat is only avoided if there is another option
+			if len(groups) != 1 {
+				t.Errorf("groups = %q, want U1 and U2 paired anyway", groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+var (
+	// pairingSchedule is nil when scheduled pairing is disabled
+	pairingSchedule *cronSchedule
+	pairingLocation = time.Local
+
+	cronMonthNames   = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	cronWeekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// cronSchedule is a crontab schedule with minute, hour, day of month, month and day of week fields.
+// Like cron, when both the day of month and the day of week are restricted, a day matching either one fires.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays []bool
+
+	anyDay, anyWeekday bool
+}
+
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	var fields = strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	var schedule = &cronSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	var err error
+
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour: %w", err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day of month: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid month: %w", err)
+	}
+	// 7 is sunday too
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid day of week: %w", err)
+	}
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+
+	if schedule.next(time.Now()).IsZero() {
+		return nil, errors.New("schedule never fires")
+	}
+
+	return schedule, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps, names are accepted in place of numbers.
+func parseCronField(field string, min int, max int, names []string) ([]bool, error) {
+	var values = make([]bool, max+1)
+
+	for _, item := range strings.Split(field, ",") {
+		var step = 1
+		if i := strings.IndexByte(item, '/'); i != -1 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", item[i+1:])
+			}
+			item = item[:i]
+		}
+
+		var from, to = min, max
+		if item != "*" {
+			var err error
+			var bounds = strings.SplitN(item, "-", 2)
+			if from, err = parseCronValue(bounds[0], min, max, names); err != nil {
+				return nil, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = parseCronValue(bounds[1], min, max, names); err != nil {
+					return nil, err
+				}
+			} else if step != 1 {
+				to = max
+			}
+			if to < from {
+				return nil, fmt.Errorf("invalid range %q", item)
+			}
+		}
+
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func parseCronValue(s string, min int, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+
+	var value, err = strconv.Atoi(s)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return value, nil
+}
+
+func (schedule *cronSchedule) dayMatches(t time.Time) bool {
+	var day, weekday = schedule.days[t.Day()], schedule.weekdays[t.Weekday()]
+
+	switch {
+	case schedule.anyDay && schedule.anyWeekday:
+		return true
+	case schedule.anyDay:
+		return weekday
+	case schedule.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// next returns the first time strictly after t matching the schedule, in the location of t.
+// It returns the zero time if the schedule doesn't match in the next five years.
+func (schedule *cronSchedule) next(t time.Time) time.Time {
+	var limit = t.AddDate(5, 0, 0)
+	var loc = t.Location()
+
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+
+	for t.Before(limit) {
+		switch {
+		case !schedule.months[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !schedule.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !schedule.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !schedule.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// runPairingSchedule sends the scheduled pairing times to the run loop, it closes pairings when ctx is done.
+func runPairingSchedule(ctx context.Context, pairings chan<- time.Time) {
+	defer close(pairings)
+
+	for {
+		var next = pairingSchedule.next(time.Now().In(pairingLocation))
+		if next.IsZero() {
+			logger.Println("warning: no more scheduled pairing")
+			return
+		}
+		logger.Printf("next scheduled pairing at %s", next)
+
+		var timer = time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case at := <-timer.C:
+			select {
+			case pairings <- at:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// handleScheduledPairing pairs the users of the pool, and reports the result to all the admins.
+func handleScheduledPairing() {
+	var users, err = getPoolCandidates()
+	if err != nil {
+		logger.Printf("error reading candidates from datastore: %s", err)
+		return
+	}
+
+	if len(users) == 0 {
+		logger.Println("skipping scheduled pairing, the pool is empty")
+		return
+	}
+
+	logger.Printf("running scheduled pairing of %d users", len(users))
+
+	autoPair("", users, false, func(render func(admin string) string) {
+		for admin := range twinLunchAdmins {
+			sendBotMessageToUser(admin, messageTo(admin, "scheduledPairing", messageData{"Report": render(admin)}))
+		}
+	})
+}
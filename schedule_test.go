@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+	// the DST changes are tested in Europe/Paris, whatever the zone database of the system
+	_ "time/tzdata"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	var paris, err = time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"weekly before spring DST", "0 9 * * MON", time.Date(2021, 3, 26, 12, 0, 0, 0, paris), time.Date(2021, 3, 29, 9, 0, 0, 0, paris)},
+		{"weekly before autumn DST", "0 9 * * MON", time.Date(2021, 10, 29, 12, 0, 0, 0, paris), time.Date(2021, 11, 1, 9, 0, 0, 0, paris)},
+		{"weekly at the scheduled time", "0 9 * * MON", time.Date(2021, 3, 29, 9, 0, 0, 0, paris), time.Date(2021, 4, 5, 9, 0, 0, 0, paris)},
+		{"weekly just before the scheduled time", "0 9 * * MON", time.Date(2021, 3, 29, 8, 59, 59, 0, paris), time.Date(2021, 3, 29, 9, 0, 0, 0, paris)},
+		{"step within the hour", "*/15 * * * *", time.Date(2021, 6, 1, 10, 7, 0, 0, paris), time.Date(2021, 6, 1, 10, 15, 0, 0, paris)},
+		{"step to the next hour", "*/15 * * * *", time.Date(2021, 6, 1, 10, 45, 0, 0, paris), time.Date(2021, 6, 1, 11, 0, 0, 0, paris)},
+		{"step to the next day", "*/15 * * * *", time.Date(2021, 6, 1, 23, 50, 0, 0, paris), time.Date(2021, 6, 2, 0, 0, 0, 0, paris)},
+		{"step across spring DST", "*/15 * * * *", time.Date(2021, 3, 28, 1, 50, 0, 0, paris), time.Date(2021, 3, 28, 3, 0, 0, 0, paris)},
+		// 02:00 happens twice on the night of the autumn DST change, the schedule fires once at 02:00 CET, 01:00 UTC
+		{"daily in the hour repeated by autumn DST", "0 2 * * *", time.Date(2021, 10, 31, 1, 0, 0, 0, paris), time.Date(2021, 10, 31, 1, 0, 0, 0, time.UTC)},
+		{"daily once after the hour repeated by autumn DST", "0 2 * * *", time.Date(2021, 10, 31, 1, 0, 0, 0, time.UTC).In(paris), time.Date(2021, 11, 1, 2, 0, 0, 0, paris)},
+		{"day of month or day of week", "0 12 1 * FRI", time.Date(2021, 6, 2, 0, 0, 0, 0, paris), time.Date(2021, 6, 4, 12, 0, 0, 0, paris)},
+		{"next year", "0 0 1 JAN *", time.Date(2021, 6, 1, 0, 0, 0, 0, paris), time.Date(2022, 1, 1, 0, 0, 0, 0, paris)},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var schedule, err = parseCronSchedule(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := schedule.next(test.from); !next.Equal(test.want) {
+				t.Errorf("next(%s) = %s, want %s", test.from, next, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+type featureScopes struct {
+	feature string
+	enabled func() bool
+	scopes  []string
+}
+
+// scopesClient bounds the scopes check, which runs at startup before the bot is ready
+var scopesClient = &http.Client{Timeout: 10 * time.Second}
+
+var requiredScopes = []featureScopes{
+	{"messages and commands", nil, []string{"chat:write", "chat:write.customize", "commands", "im:history", "im:write"}},
+	{"user checks", nil, []string{"users:read"}},
+	{"activity and graph exports", nil, []string{"files:write"}},
+	{"file forwarding", nil, []string{"files:read", "files:write"}},
+	{"pairing from reactions", nil, []string{"reactions:read", "users:read"}},
+	{"reaction relay", nil, []string{"reactions:read", "reactions:write"}},
+	{"intro pinning", func() bool { return pinIntro }, []string{"pins:read", "pins:write"}},
+	{"channel topic", func() bool { return topicChannel != "" }, []string{"channels:manage"}},
+}
+
+// getGrantedScopes calls auth.test directly, the slack client doesn't expose the scopes header.
+func getGrantedScopes(token string) (map[string]struct{}, error) {
+	var req, err = http.NewRequest(http.MethodPost, slack.APIURL+"auth.test", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating auth test request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := scopesClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error testing slack authentication: %w", err)
+	}
+	resp.Body.Close()
+
+	var scopes = make(map[string]struct{})
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes[scope] = struct{}{}
+		}
+	}
+
+	return scopes, nil
+}
+
+// checkScopes logs the features which miss scopes, the readiness check reports them too.
+func checkScopes(token string) {
+	var granted, err = getGrantedScopes(token)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+
+	var warnings []string
+
+	for _, required := range requiredScopes {
+		if required.enabled != nil && !required.enabled() {
+			continue
+		}
+
+		var missing []string
+		for _, scope := range required.scopes {
+			if _, ok := granted[scope]; !ok {
+				missing = append(missing, scope)
+			}
+		}
+
+		if len(missing) != 0 {
+			var warning = fmt.Sprintf("%s requires missing scopes %s", required.feature, strings.Join(missing, ", "))
+			logger.Printf("warning: %s", warning)
+			warnings = append(warnings, warning)
+		}
+	}
+
+	setScopeWarnings(warnings)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	shutdownTimeout = 10 * time.Second
+
+	// loops counts the goroutines which must return before the scheduled deliveries are awaited
+	loops sync.WaitGroup
+
+	// deliveries counts the bot and relayed messages which are scheduled but not sent yet
+	deliveries sync.WaitGroup
+)
+
+// shutdown stops the HTTP server, waits for the run loop and the scheduled deliveries, then closes the datastore client.
+// The whole procedure is bounded by shutdownTimeout, undelivered messages are lost past this delay.
+func shutdown(server *http.Server) {
+	logger.Println("shutting down...")
+
+	var ctx, cancel = context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Printf("error shutting down http server: %s", err)
+	}
+
+	if !waitGroup(ctx, &loops) {
+		logger.Println("warning: run loop didn't return before shutdown timeout")
+	}
+
+	if !waitGroup(ctx, &deliveries) {
+		logger.Println("warning: some messages weren't delivered before shutdown timeout")
+	}
+
+	if datastoreClient != nil {
+		if err := datastoreClient.Close(); err != nil {
+			logger.Printf("error closing datastore client: %s", err)
+		}
+	}
+
+	logger.Println("shut down")
+}
+
+// waitGroup waits for wg, it returns false if ctx is done first.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) bool {
+	var done = make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// eventAcknowledger acknowledges the events received from slack, it is the socket mode client outside of the tests.
+type eventAcknowledger interface {
+	Ack(req socketmode.Request, payload ...interface{})
+}
+
+// slackAPI is the part of the slack web API used by the bot, it allows replacing the slack client with a fake one.
+type slackAPI interface {
+	AuthTest() (*slack.AuthTestResponse, error)
+	GetUsersInfo(users ...string) (*[]slack.User, error)
+
+	OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+	SetTopicOfConversation(channel string, topic string) (*slack.Channel, error)
+
+	PostMessage(channel string, options ...slack.MsgOption) (string, string, error)
+	UpdateMessage(channel string, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	DeleteMessage(channel string, timestamp string) (string, string, error)
+
+	AddPin(channel string, item slack.ItemRef) error
+	RemovePin(channel string, item slack.ItemRef) error
+	ListPins(channel string) ([]slack.Item, *slack.Paging, error)
+
+	AddReaction(name string, item slack.ItemRef) error
+	RemoveReaction(name string, item slack.ItemRef) error
+	GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error)
+
+	PublishView(userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error)
+
+	GetFile(downloadURL string, writer io.Writer) error
+	UploadFile(params slack.FileUploadParameters) (*slack.File, error)
+}
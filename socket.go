@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	// slackReconnectRetries is the number of consecutive failed runs of the slack client before giving up
+	slackReconnectRetries    = 10
+	slackReconnectMinBackoff = time.Second
+	slackReconnectMaxBackoff = 2 * time.Minute
+	// slackReconnectResetAfter is how long the slack client must stay up for the retries to be reset
+	slackReconnectResetAfter = time.Minute
+)
+
+// runSlackClient runs the slack client until ctx is done, reconnecting with exponential backoff and jitter.
+func runSlackClient(ctx context.Context) {
+	var backoff = slackReconnectMinBackoff
+
+	for attempt := 1; ; attempt++ {
+		logger.Println("running slack client...")
+
+		var startedAt = time.Now()
+		var err = socketClient.RunContext(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if isSlackAuthError(err) {
+			logger.Fatalf("error running slack client: %s", err)
+		}
+
+		if time.Since(startedAt) >= slackReconnectResetAfter {
+			attempt, backoff = 1, slackReconnectMinBackoff
+		}
+
+		if attempt > slackReconnectRetries {
+			logger.Fatalf("error running slack client, giving up after %d attempts: %s", slackReconnectRetries, err)
+		}
+
+		var wait = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		logger.Printf("error running slack client, reconnecting in %s (attempt %d/%d): %s", wait, attempt, slackReconnectRetries, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if backoff *= 2; backoff > slackReconnectMaxBackoff {
+			backoff = slackReconnectMaxBackoff
+		}
+	}
+}
+
+// isSlackAuthError tells if err is one of the errors for which the slack client doesn't retry to connect.
+func isSlackAuthError(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return false
+	}
+
+	switch slackErr.Err {
+	case "invalid_auth", "not_authed", "account_inactive", "token_revoked", "invalid_token":
+		return true
+	}
+
+	return false
+}
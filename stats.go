@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+	"google.golang.org/api/iterator"
+)
+
+// maxPutMulti is the maximum number of entities written by a datastore PutMulti, or deleted by a DeleteMulti
+const maxPutMulti = 500
+
+var (
+	// twinLunchSaveInterval spaces the writes of the message counts and disclaimer flags, which change with every relayed message
+	twinLunchSaveInterval = time.Minute
+
+	// statsTimeout bounds the history scan of /twinlunch-stats
+	statsTimeout = 5 * time.Minute
+
+	// unsavedTwinLunches are the pairings changed by relayed messages since they were saved, it is only used from the run loop
+	unsavedTwinLunches = make(map[*TwinLunch]struct{})
+)
+
+type roundStats struct {
+	Round    int
+	Pairs    int
+	Chatty   int
+	Messages int
+}
+
+func (stats *roundStats) add(messages int) {
+	stats.Pairs++
+	stats.Messages += messages
+	if messages != 0 {
+		stats.Chatty++
+	}
+}
+
+type activePairData struct {
+	pairData
+	Messages int
+}
+
+// getRoundStats streams the whole history, the message counts of the ongoing pairings are given by their ID.
+// The scan isn't retried, ctx bounds it as a whole.
+func getRoundStats(ctx context.Context, active map[int64]int) ([]roundStats, error) {
+	var byRound = make(map[int]*roundStats)
+
+	var it = datastoreClient.Run(ctx, newDatastoreQuery("TwinLunchHistory"))
+
+	for {
+		var history TwinLunchHistory
+		var key, err = it.Next(&history)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error listing history in datastore: %w", err)
+		}
+
+		var messages = history.Messages
+		if count, ok := active[key.ID]; ok {
+			messages = count
+		}
+
+		var stats, ok = byRound[history.Round]
+		if !ok {
+			stats = &roundStats{Round: history.Round}
+			byRound[history.Round] = stats
+		}
+		stats.add(messages)
+	}
+
+	var rounds = make([]roundStats, 0, len(byRound))
+	for _, stats := range byRound {
+		rounds = append(rounds, *stats)
+	}
+	sort.Slice(rounds, func(i, j int) bool {
+		return rounds[i].Round < rounds[j].Round
+	})
+
+	return rounds, nil
+}
+
+func handleStatsCommand(command slack.SlashCommand) {
+	// the message counts change with the relayed messages, they are read on the run loop
+	var active = make(map[int64]int)
+	var chatty, silent []activePairData
+	for _, twinLunch := range twinLunches.List() {
+		if twinLunch.Key != nil {
+			active[twinLunch.Key.ID] = twinLunch.Messages
+		}
+		var pair = activePairData{newPairData(twinLunch.Members()), twinLunch.Messages}
+		if twinLunch.Messages == 0 {
+			silent = append(silent, pair)
+		} else {
+			chatty = append(chatty, pair)
+		}
+	}
+	sort.Slice(chatty, func(i, j int) bool {
+		return chatty[i].Messages > chatty[j].Messages
+	})
+
+	// the history scan may take a while, it mustn't hold the run loop
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+		sendStats(command, active, chatty, silent)
+	}()
+}
+
+// sendStats reports the statistics of the rounds and of the ongoing pairings through a placeholder message.
+func sendStats(command slack.SlashCommand, active map[int64]int, chatty []activePairData, silent []activePairData) {
+	var channel, ts = sendPlaceholderToUser(command.UserID)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	var rounds, err = getRoundStats(ctx, active)
+	if err != nil {
+		commandLogger(command).Println(err)
+		replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	var total roundStats
+	for _, stats := range rounds {
+		total.Pairs += stats.Pairs
+		total.Chatty += stats.Chatty
+		total.Messages += stats.Messages
+	}
+
+	replacePlaceholder(command.UserID, channel, ts, messageTo(command.UserID, "stats", messageData{
+		"Rounds": rounds,
+		"Total":  total,
+		"Chatty": chatty,
+		"Silent": silent,
+	}))
+}
+
+// saveUnsavedTwinLunches writes the changed pairings in batches, the ones which ended meanwhile are dropped.
+// The pairings which couldn't be written are kept for the next save.
+func saveUnsavedTwinLunches() {
+	var changed []*TwinLunch
+	for twinLunch := range unsavedTwinLunches {
+		if current, ok := twinLunches.Get(twinLunch.User1); ok && current == twinLunch && twinLunch.Key != nil {
+			changed = append(changed, twinLunch)
+		}
+	}
+	unsavedTwinLunches = make(map[*TwinLunch]struct{})
+
+	for len(changed) != 0 {
+		var batch = changed
+		if len(batch) > maxPutMulti {
+			batch = batch[:maxPutMulti]
+		}
+		changed = changed[len(batch):]
+
+		var keys = make([]*datastore.Key, len(batch))
+		var saved = make([]TwinLunch, len(batch))
+		for i, twinLunch := range batch {
+			keys[i], saved[i] = twinLunch.Key, *twinLunch
+		}
+
+		if err := withDatastore(context.Background(), func(ctx context.Context) error {
+			var _, err = datastoreClient.PutMulti(ctx, keys, saved)
+			return err
+		}); err != nil {
+			logger.Printf("error writing twin lunches in datastore: %s", err)
+			for _, twinLunch := range batch {
+				unsavedTwinLunches[twinLunch] = struct{}{}
+			}
+		}
+	}
+}
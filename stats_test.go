@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func storedMessageCount(t *testing.T, fd *fakeDatastore, twinLunch *TwinLunch) int {
+	t.Helper()
+
+	var stored TwinLunch
+	if err := fd.Get(context.Background(), twinLunch.Key, &stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored.Messages
+}
+
+func TestSaveMessageCounts(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	var savedRateLimit = relayRateLimit
+	relayRateLimit = 0
+	t.Cleanup(func() { relayRateLimit = savedRateLimit })
+
+	var chatty, err = createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ended, err := createTwinLunch([]string{"U3", "U4"}, 0, "UADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Wait()
+
+	for _, user := range []string{"U1", "U2", "U1", "U3"} {
+		handleMessage(&slackevents.MessageEvent{Channel: dmChannel(user), User: user, ChannelType: slack.TYPE_IM, Text: "hello"})
+	}
+	deliveries.Wait()
+	twinLunches.Unpair(ended)
+
+	if messages := storedMessageCount(t, fd, chatty); messages != 0 {
+		t.Errorf("stored message count while relaying = %d, want it unsaved", messages)
+	}
+
+	saveUnsavedTwinLunches()
+
+	if messages := storedMessageCount(t, fd, chatty); messages != 3 {
+		t.Errorf("stored message count = %d, want 3", messages)
+	}
+	if messages := storedMessageCount(t, fd, ended); messages != 0 {
+		t.Errorf("stored message count of the ended twin lunch = %d, want it unsaved", messages)
+	}
+	if len(unsavedTwinLunches) != 0 {
+		t.Errorf("unsaved twin lunches after saving = %d, want none", len(unsavedTwinLunches))
+	}
+}
+
+func TestHandleStatsCommand(t *testing.T) {
+	var fs, fd = setupFakes(t)
+
+	// the scan is bound by statsTimeout, not by the timeout of a datastore call
+	var savedTimeout = datastoreTimeout
+	datastoreTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { datastoreTimeout = savedTimeout })
+
+	var twinLunch, err = createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	twinLunch.Messages = 3
+	if _, err := fd.Put(context.Background(), datastore.IDKey("TwinLunchHistory", twinLunch.Key.ID+1, nil), &TwinLunchHistory{Users: []string{"U3", "U4"}, Round: 1}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Wait()
+	fd.delayNext("Run", 50*time.Millisecond)
+
+	var start = time.Now()
+	handleStatsCommand(slack.SlashCommand{Command: "/twinlunch-stats", UserID: "UADMIN"})
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("command took %s, want the stats to be computed in the background", elapsed)
+	}
+	deliveries.Wait()
+
+	var want = messageTo("UADMIN", "stats", messageData{
+		"Rounds": []roundStats{{Round: 1, Pairs: 2, Chatty: 1, Messages: 3}},
+		"Total":  roundStats{Pairs: 2, Chatty: 1, Messages: 3},
+		"Chatty": []activePairData{{newPairData([]string{"U1", "U2"}), 3}},
+		"Silent": []activePairData(nil),
+	})
+	if len(fs.updated) != 1 || !strings.Contains(fs.updated[0].Text, want) {
+		t.Errorf("placeholder updates = %q, want %q", fs.updated, want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	datastoreTimeout = 10 * time.Second
+	datastoreRetries = 3
+
+	transactionSlots     = make(chan struct{}, 4)
+	transactionsInFlight = expvar.NewInt("datastore_transactions_in_flight")
+)
+
+// withDatastore runs a datastore operation with a timeout, retrying it on transient errors.
+// f must be safe to run more than once.
+func withDatastore(parent context.Context, f func(ctx context.Context) error) error {
+	var backoff = 100 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		var ctx, cancel = context.WithTimeout(parent, datastoreTimeout)
+		var err = f(ctx)
+		cancel()
+
+		if err == nil || attempt >= datastoreRetries || !isTransientDatastoreError(err) || parent.Err() != nil {
+			return err
+		}
+
+		logger.With("attempt", attempt+1, "backoff", backoff.String()).Printf("retrying datastore operation after error: %s", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// allocateKeys allocates n keys of kind under parent.
+// Writing with allocated keys rather than incomplete ones makes retries safe, they don't create duplicates.
+func allocateKeys(kind string, parent *datastore.Key, n int) ([]*datastore.Key, error) {
+	if n == 0 {
+		return nil, nil
+	}
+
+	var incomplete = make([]*datastore.Key, n)
+	for i := range incomplete {
+		incomplete[i] = datastore.IncompleteKey(kind, parent)
+	}
+
+	var keys []*datastore.Key
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var err error
+		keys, err = datastoreClient.AllocateIDs(ctx, incomplete)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("error allocating keys in datastore: %w", err)
+	}
+
+	return keys, nil
+}
+
+func isTransientDatastoreError(err error) bool {
+	if errors.Is(err, datastore.ErrConcurrentTransaction) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.Aborted, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted, codes.Unavailable:
+		return true
+	}
+
+	return false
+}
+
+// runInTransaction bounds the number of concurrent datastore transactions.
+func runInTransaction(ctx context.Context, f func(tx datastoreTransaction) error) error {
+	select {
+	case transactionSlots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	transactionsInFlight.Add(1)
+	defer func() {
+		transactionsInFlight.Add(-1)
+		<-transactionSlots
+	}()
+
+	return datastoreClient.RunInTransaction(ctx, f)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithDatastore(t *testing.T) {
+	var savedTimeout, savedRetries = datastoreTimeout, datastoreRetries
+	datastoreTimeout, datastoreRetries = 20*time.Millisecond, 2
+	t.Cleanup(func() { datastoreTimeout, datastoreRetries = savedTimeout, savedRetries })
+
+	var errDown = errors.New("datastore is down")
+
+	var tests = []struct {
+		name         string
+		delays       []time.Duration
+		failures     []error
+		wantErr      error
+		wantAttempts int
+		wantStored   bool
+	}{
+		{name: "fast", wantAttempts: 1, wantStored: true},
+		{name: "slow once", delays: []time.Duration{time.Second}, wantAttempts: 2, wantStored: true},
+		{name: "always slow", delays: []time.Duration{time.Second, time.Second, time.Second}, wantErr: context.DeadlineExceeded, wantAttempts: 3},
+		{name: "transient error", failures: []error{status.Error(codes.Unavailable, "unavailable")}, wantAttempts: 2, wantStored: true},
+		{name: "permanent error", failures: []error{errDown}, wantErr: errDown, wantAttempts: 1},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var _, fd = setupFakes(t)
+			fd.delayNext("Put", test.delays...)
+			fd.failNext("Put", test.failures...)
+
+			var key = datastore.NameKey("TwinLunchUser", "U1", nil)
+			var err = withDatastore(context.Background(), func(ctx context.Context) error {
+				var _, err = fd.Put(ctx, key, &TwinLunchUser{NoNotifications: true})
+				return err
+			})
+
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("error = %v, want %v", err, test.wantErr)
+			}
+			if attempts := fd.callCount("Put"); attempts != test.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, test.wantAttempts)
+			}
+			if stored := fd.Get(context.Background(), key, &TwinLunchUser{}) == nil; stored != test.wantStored {
+				t.Errorf("stored = %t, want %t", stored, test.wantStored)
+			}
+		})
+	}
+}
+
+func TestRunInTransactionLimit(t *testing.T) {
+	var _, fd = setupFakes(t)
+
+	var savedSlots = transactionSlots
+	transactionSlots = make(chan struct{}, 2)
+	t.Cleanup(func() { transactionSlots = savedSlots })
+
+	// the first transactions hold their slot until they are canceled
+	fd.delayNext("RunInTransaction", time.Hour, time.Hour)
+	var ctx, cancel = context.WithCancel(context.Background())
+	var errs = make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- runInTransaction(ctx, func(tx datastoreTransaction) error { return nil })
+		}()
+	}
+
+	var deadline = time.Now().Add(5 * time.Second)
+	for transactionsInFlight.Value() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("transactions in flight = %d, want 2", transactionsInFlight.Value())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var waitCtx, waitCancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	if err := runInTransaction(waitCtx, func(tx datastoreTransaction) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error of the transaction over the limit = %v, want %v", err, context.DeadlineExceeded)
+	}
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("error of a canceled transaction = %v, want %v", err, context.Canceled)
+		}
+	}
+	if calls := fd.callCount("RunInTransaction"); calls != 2 {
+		t.Errorf("transactions run = %d, want only the 2 under the limit", calls)
+	}
+	if inFlight := transactionsInFlight.Value(); inFlight != 0 {
+		t.Errorf("transactions in flight after cancel = %d, want 0", inFlight)
+	}
+
+	// the slots are released
+	if err := runInTransaction(context.Background(), func(tx datastoreTransaction) error { return nil }); err != nil {
+		t.Errorf("error after the slots are released = %v", err)
+	}
+}
+
+func TestHandleAddCommandDatastoreTimeout(t *testing.T) {
+	var fs, fd = setupFakes(t)
+	twinLunchAdmins["UADMIN"] = struct{}{}
+
+	var savedTimeout, savedRetries = datastoreTimeout, datastoreRetries
+	datastoreTimeout, datastoreRetries = 20*time.Millisecond, 1
+	t.Cleanup(func() { datastoreTimeout, datastoreRetries = savedTimeout, savedRetries })
+
+	// every attempt hangs past the timeout
+	fd.delayNext("AllocateIDs", time.Hour, time.Hour)
+
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		handleCommand(slack.SlashCommand{Command: "/twinlunch-add", UserID: "UADMIN", Text: "<@U1> <@U2>"})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler is still waiting for datastore")
+	}
+	deliveries.Wait()
+
+	checkMessages(t, fs, map[string][]string{
+		dmChannel("UADMIN"): {message("datastoreError", nil)},
+		dmChannel("U1"):     nil,
+		dmChannel("U2"):     nil,
+	})
+	if attempts := fd.callCount("AllocateIDs"); attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if stored := storedTwinLunches(t, fd); len(stored) != 0 {
+		t.Errorf("stored twin lunches = %q, want none", stored)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+)
+
+var (
+	pairingSummary bool
+	nextRoundURL   string
+)
+
+// sendTwinLunchEnded tells users that their pairing ended, with the pairing summary if it is enabled.
+// The summary is optional, users who turned it off get a short essential notice instead.
+func sendTwinLunchEnded(twinLunch *TwinLunch, users []string) {
+	for _, user := range users {
+		if pairingSummary && !getTwinLunchUser(user).NoNotifications {
+			sendNotificationToUser(user, optionalMessage, pairingSummaryText(twinLunch, user))
+		} else {
+			sendNotificationToUser(user, essentialMessage, messageTo(user, "ended", messageData{"Round": twinLunch.Round}))
+		}
+	}
+}
+
+func pairingSummaryText(twinLunch *TwinLunch, user string) string {
+	if !pairingSummary {
+		return ""
+	}
+
+	// days is negative when the pairing date is unknown
+	var days = -1
+	if !twinLunch.CreatedAt.IsZero() {
+		days = int(time.Since(twinLunch.CreatedAt).Hours() / 24)
+	}
+
+	return messageTo(user, "pairingSummary", messageData{
+		"Round":    twinLunch.Round,
+		"Days":     days,
+		"Messages": twinLunch.Messages,
+		"URL":      nextRoundURL,
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+// handleSwapCommand turns the pairs (A, B) and (C, D) into (A, C) and (B, D).
+// The members are told that their partner changed instead of getting the end of their Twin Lunch.
+func handleSwapCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 4 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "swapUsage", nil))
+		return
+	}
+
+	var a, b, c, d = matches[0][1], matches[1][1], matches[2][1], matches[3][1]
+
+	var first, ok = getPair(a, b)
+	if !ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notPaired", messageData{"User1": a, "User2": b}))
+		return
+	}
+
+	second, ok := getPair(c, d)
+	if !ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notPaired", messageData{"User1": c, "User2": d}))
+		return
+	}
+
+	if first == second {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "swapSamePair", nil))
+		return
+	}
+
+	for _, pair := range [][2]string{{a, c}, {b, d}} {
+		if exclusion, err := getExclusion(pair[0], pair[1]); err != nil {
+			commandLogger(command).Println(err)
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+			return
+		} else if exclusion != nil {
+			sendBotMessageToUser(command.UserID, messageTo(command.UserID, "excludedPair", messageData{"User1": pair[0], "User2": pair[1], "Reason": exclusion.Reason}))
+			return
+		}
+	}
+
+	// the tables of /twinlunch-add-numbered are kept, A and D stay at theirs
+	var swapped = []*TwinLunch{
+		newTwinLunch([]string{a, c}, first.Slot, command.UserID),
+		newTwinLunch([]string{b, d}, second.Slot, command.UserID),
+	}
+
+	if err := saveSwappedTwinLunches([]*TwinLunch{first, second}, swapped); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	for _, twinLunch := range []*TwinLunch{first, second} {
+		twinLunches.Unpair(twinLunch)
+		onTwinLunchClosed(twinLunch, command.UserID)
+		cleanupRelayedMessages(twinLunch, false)
+	}
+
+	recordAudit(auditActionPairsSwapped, command.UserID, a, b, c, d)
+
+	// the notice is queued before the intro of the new pairing
+	for _, user := range []string{a, b, c, d} {
+		sendNotificationToUser(user, essentialMessage, messageTo(user, "partnerSwapped", nil))
+	}
+
+	for _, twinLunch := range swapped {
+		onTwinLunchCreated(twinLunch, command.UserID)
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "swapped", messageData{"User1": a, "User2": b, "User3": c, "User4": d}))
+}
+
+// getPair returns the pairing of user1 and user2 if they are paired together, without anybody else.
+func getPair(user1 string, user2 string) (*TwinLunch, bool) {
+	var twinLunch, ok = twinLunches.Get(user1)
+	if !ok || user1 == user2 || len(twinLunch.Others) != 0 || twinLunch.memberIndex(user2) == -1 {
+		return nil, false
+	}
+	return twinLunch, true
+}
+
+// saveSwappedTwinLunches deletes the previous pairings and writes the swapped ones in a single transaction.
+func saveSwappedTwinLunches(previous []*TwinLunch, swapped []*TwinLunch) error {
+	var deleted = make([]*datastore.Key, 0, len(previous))
+	for _, twinLunch := range previous {
+		if twinLunch.Key == nil {
+			return errors.New("could not find twin lunch in datastore")
+		}
+		deleted = append(deleted, twinLunch.Key)
+	}
+
+	var keys, err = allocateKeys("TwinLunch", twinLunchListKey, len(swapped))
+	if err != nil {
+		return err
+	}
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		return runInTransaction(ctx, func(tx datastoreTransaction) error {
+			if err := tx.DeleteMulti(deleted); err != nil {
+				return fmt.Errorf("error deleting keys in datastore: %w", err)
+			}
+			if _, err := tx.PutMulti(keys, swapped); err != nil {
+				return fmt.Errorf("error writing keys in datastore: %w", err)
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	for i, twinLunch := range swapped {
+		twinLunch.Key = keys[i]
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestHandleSwapCommandKeepsTables(t *testing.T) {
+	setupFakes(t)
+
+	for i, users := range [][]string{{"U1", "U2"}, {"U3", "U4"}} {
+		if _, err := createTwinLunch(users, i+1, "UADMIN"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliveries.Wait()
+
+	handleSwapCommand(slack.SlashCommand{Command: "/twinlunch-swap", UserID: "UADMIN", Text: "<@U1> <@U2> <@U3> <@U4>"})
+	deliveries.Wait()
+
+	for user, want := range map[string]int{"U1": 1, "U3": 1, "U2": 2, "U4": 2} {
+		var twinLunch, ok = twinLunches.Get(user)
+		if !ok {
+			t.Errorf("%s isn't paired", user)
+		} else if twinLunch.Slot != want {
+			t.Errorf("%s is at table %d, want table %d", user, twinLunch.Slot, want)
+		}
+	}
+}
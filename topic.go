@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	// topicChannel is the channel whose topic shows the round status, empty disables it
+	topicChannel string
+	// topicClearOnClear empties the topic on /twinlunch-clear instead of showing zero pairs
+	topicClearOnClear bool
+	// topicUpdateDelay groups the pairing changes of a busy round into a single topic update
+	topicUpdateDelay = 30 * time.Second
+
+	topicMu        sync.Mutex
+	topicText      string
+	topicScheduled bool
+	topicDisabled  bool
+)
+
+// updateTopic schedules a topic update with the current round status, it must be called from the run loop.
+func updateTopic() {
+	if topicChannel == "" {
+		return
+	}
+
+	scheduleTopic(message("topic", messageData{"Round": currentRound, "Pairs": twinLunches.Len()}))
+}
+
+func clearTopic() {
+	if topicChannel == "" {
+		return
+	}
+
+	if !topicClearOnClear {
+		updateTopic()
+		return
+	}
+
+	scheduleTopic("")
+}
+
+func scheduleTopic(topic string) {
+	topicMu.Lock()
+	defer topicMu.Unlock()
+
+	if topicDisabled {
+		return
+	}
+
+	topicText = topic
+
+	if !topicScheduled {
+		topicScheduled = true
+		time.AfterFunc(topicUpdateDelay, setTopic)
+	}
+}
+
+func setTopic() {
+	topicMu.Lock()
+	var topic = topicText
+	topicScheduled = false
+	topicMu.Unlock()
+
+	if _, err := slackClient.SetTopicOfConversation(topicChannel, topic); err != nil {
+		var slackErr slack.SlackErrorResponse
+		if errors.As(err, &slackErr) && isTopicPermissionError(slackErr.Err) {
+			logger.Printf("warning: disabling topic updates, the bot can't set the topic of %s: %s", topicChannel, err)
+			topicMu.Lock()
+			topicDisabled = true
+			topicMu.Unlock()
+			return
+		}
+		logger.Printf("error setting channel topic: %s", err)
+	}
+}
+
+func isTopicPermissionError(code string) bool {
+	switch code {
+	case "missing_scope", "not_in_channel", "channel_not_found", "restricted_action", "is_archived":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import "sync"
+
+// twinLunchStore indexes the ongoing pairings by user, it is safe for concurrent use.
+// The pairings themselves are only modified from the run loop.
+type twinLunchStore struct {
+	mu     sync.RWMutex
+	byUser map[string]*TwinLunch
+}
+
+func newTwinLunchStore() *twinLunchStore {
+	return &twinLunchStore{byUser: make(map[string]*TwinLunch)}
+}
+
+func (s *twinLunchStore) Get(user string) (*TwinLunch, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var twinLunch, ok = s.byUser[user]
+	return twinLunch, ok
+}
+
+func (s *twinLunchStore) Pair(twinLunch *TwinLunch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range twinLunch.Members() {
+		s.byUser[user] = twinLunch
+	}
+}
+
+func (s *twinLunchStore) Unpair(twinLunch *TwinLunch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range twinLunch.Members() {
+		delete(s.byUser, user)
+	}
+}
+
+// Replace replaces previous with updated at once, the members of previous who aren't in updated are unpaired.
+func (s *twinLunchStore) Replace(previous *TwinLunch, updated *TwinLunch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range previous.Members() {
+		delete(s.byUser, user)
+	}
+	for _, user := range updated.Members() {
+		s.byUser[user] = updated
+	}
+}
+
+// Clear removes all the pairings and returns them.
+func (s *twinLunchStore) Clear() []*TwinLunch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list = s.list()
+	s.byUser = make(map[string]*TwinLunch)
+	return list
+}
+
+// List returns each pairing once.
+func (s *twinLunchStore) List() []*TwinLunch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list()
+}
+
+func (s *twinLunchStore) list() []*TwinLunch {
+	var list = make([]*TwinLunch, 0, len(s.byUser)/2)
+	for user, twinLunch := range s.byUser {
+		if user == twinLunch.User1 {
+			list = append(list, twinLunch)
+		}
+	}
+	return list
+}
+
+// Len returns the number of pairings.
+func (s *twinLunchStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.list())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+var (
+	// unpairedReplyOnce replies once to unpaired users, then stays silent until unpairedReminderAfter has elapsed
+	unpairedReplyOnce     bool
+	unpairedReminderAfter = 24 * time.Hour
+
+	// unpairedReplies is only accessed from the run loop
+	unpairedReplies = make(map[string]time.Time)
+)
+
+func replyUnpaired(event *slackevents.MessageEvent) {
+	if !unpairedReplyOnce {
+		sendBotMessageToChannel(event.Channel, messageTo(event.User, "noTwinLunch", nil))
+		return
+	}
+
+	var lastReply, replied = unpairedReplies[event.User]
+
+	switch {
+	case !replied:
+		sendBotMessageToChannel(event.Channel, messageTo(event.User, "noTwinLunch", nil))
+
+	case time.Since(lastReply) >= unpairedReminderAfter:
+		sendBotMessageToChannel(event.Channel, messageTo(event.User, "nextRound", messageData{"URL": nextRoundURL}))
+
+	default:
+		return
+	}
+
+	unpairedReplies[event.User] = time.Now()
+}
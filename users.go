@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/slack-go/slack"
+)
+
+// TwinLunchUser holds the preferences and state of a user, keyed by user ID.
+type TwinLunchUser struct {
+	NoNotifications bool
+	// CooldownUntil is when the user can be automatically paired again after a pairing end
+	CooldownUntil time.Time
+	// PendingIntro is set when sending the intro failed, it is retried on the next interaction or sweep
+	PendingIntro bool
+	// PriorityRounds is the number of rounds during which the user is paired first
+	PriorityRounds int
+	// Language is the language of the messages sent to the user, empty for the one of their Slack account
+	Language string `datastore:",noindex"`
+}
+
+type messageKind int
+
+const (
+	// essentialMessage is always sent: intros, pairing changes, replies to commands...
+	essentialMessage messageKind = iota
+	// optionalMessage may be turned off by the user: reminders, icebreakers, summaries...
+	optionalMessage
+)
+
+const cooldownLayout = "2006-01-02 15:04"
+
+var (
+	repairCooldown time.Duration
+
+	twinLunchUsersMu sync.Mutex
+	twinLunchUsers   = make(map[string]*TwinLunchUser)
+	// twinLunchUserWrites holds the lock of the writes of each user, it is guarded by twinLunchUsersMu
+	twinLunchUserWrites = make(map[string]*sync.Mutex)
+)
+
+func twinLunchUserKey(user string) *datastore.Key {
+	return datastore.NameKey("TwinLunchUser", user, nil)
+}
+
+func getTwinLunchUser(user string) TwinLunchUser {
+	twinLunchUsersMu.Lock()
+	defer twinLunchUsersMu.Unlock()
+
+	if twinLunchUser, ok := twinLunchUsers[user]; ok {
+		return *twinLunchUser
+	}
+	return TwinLunchUser{}
+}
+
+// updateTwinLunchUser writes the updated user without holding twinLunchUsersMu, the write may be retried for a while.
+// The updates of a user are serialized, so that concurrent updates of different fields aren't lost.
+func updateTwinLunchUser(user string, update func(*TwinLunchUser)) error {
+	twinLunchUsersMu.Lock()
+	var writeMu, ok = twinLunchUserWrites[user]
+	if !ok {
+		writeMu = &sync.Mutex{}
+		twinLunchUserWrites[user] = writeMu
+	}
+	twinLunchUsersMu.Unlock()
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	var updated = getTwinLunchUser(user)
+	update(&updated)
+
+	if err := withDatastore(context.Background(), func(ctx context.Context) error {
+		var _, err = datastoreClient.Put(ctx, twinLunchUserKey(user), &updated)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error writing user in datastore: %w", err)
+	}
+
+	twinLunchUsersMu.Lock()
+	twinLunchUsers[user] = &updated
+	twinLunchUsersMu.Unlock()
+
+	return nil
+}
+
+func loadTwinLunchUsers(ctx context.Context) {
+	logger.Println("loading twin lunch users...")
+
+	var result []*TwinLunchUser
+	var keys []*datastore.Key
+
+	if err := withDatastore(ctx, func(ctx context.Context) error {
+		result = nil
+		var err error
+		keys, err = datastoreClient.GetAll(ctx, newDatastoreQuery("TwinLunchUser"), &result)
+		return err
+	}); err != nil {
+		logger.Fatalf("error reading twin lunch users from datastore %s", err)
+	}
+
+	twinLunchUsersMu.Lock()
+	defer twinLunchUsersMu.Unlock()
+
+	for i, twinLunchUser := range result {
+		twinLunchUsers[keys[i].Name] = twinLunchUser
+	}
+
+	logger.Printf("loaded %d twin lunch users", len(result))
+}
+
+func sendNotificationToUser(user string, kind messageKind, text string) {
+	if kind == optionalMessage && getTwinLunchUser(user).NoNotifications {
+		return
+	}
+
+	sendBotMessageToUser(user, text)
+}
+
+func handlePrefsCommand(command slack.SlashCommand) {
+	var args = strings.Fields(command.Text)
+
+	if len(args) == 0 {
+		var twinLunchUser = getTwinLunchUser(command.UserID)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "prefs", messageData{"Notifications": !twinLunchUser.NoNotifications, "Language": twinLunchUser.Language}))
+		return
+	}
+
+	if len(args) == 2 && args[0] == "language" {
+		handleLanguagePref(command, args[1])
+		return
+	}
+
+	if len(args) != 2 || args[0] != "notifications" || (args[1] != "on" && args[1] != "off") {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "prefsUsage", nil))
+		return
+	}
+
+	var noNotifications = args[1] == "off"
+
+	if err := updateTwinLunchUser(command.UserID, func(twinLunchUser *TwinLunchUser) {
+		twinLunchUser.NoNotifications = noNotifications
+	}); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	if noNotifications {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notificationsOff", nil))
+	} else {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "notificationsOn", nil))
+	}
+}
+
+// handleLanguagePref sets the language of the messages sent to the user, "auto" goes back to the one of their Slack account.
+func handleLanguagePref(command slack.SlashCommand, lang string) {
+	if _, ok := messageCatalogs[lang]; !ok && lang != "auto" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "prefsUsage", nil))
+		return
+	}
+	if lang == "auto" {
+		lang = ""
+	}
+
+	if err := updateTwinLunchUser(command.UserID, func(twinLunchUser *TwinLunchUser) {
+		twinLunchUser.Language = lang
+	}); err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	if lang == "" {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "languageAuto", nil))
+	} else {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "languageSet", nil))
+	}
+}
+
+func startCooldown(user string) {
+	if repairCooldown == 0 {
+		return
+	}
+
+	var until = time.Now().Add(repairCooldown)
+
+	if err := updateTwinLunchUser(user, func(twinLunchUser *TwinLunchUser) {
+		twinLunchUser.CooldownUntil = until
+	}); err != nil {
+		logger.Println(err)
+	}
+}
+
+func inCooldown(user string) bool {
+	return time.Now().Before(getTwinLunchUser(user).CooldownUntil)
+}
+
+func handleInspectCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "inspectUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+	var twinLunchUser = getTwinLunchUser(user)
+
+	var count, err = countProgramTwinLunches(user)
+	if err != nil {
+		commandLogger(command).Println(err)
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "datastoreError", nil))
+		return
+	}
+
+	var partner, cooldownUntil string
+	var others []string
+	if twinLunch, ok := twinLunches.Get(user); ok {
+		var partners = twinLunch.Partners(user)
+		partner, others = partners[0], partners[1:]
+	}
+	if inCooldown(user) {
+		cooldownUntil = twinLunchUser.CooldownUntil.Format(cooldownLayout)
+	}
+
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "inspect", messageData{
+		"User":            user,
+		"Partner":         partner,
+		"Others":          others,
+		"CooldownUntil":   cooldownUntil,
+		"Count":           count,
+		"Max":             maxTwinLunchesPerUser,
+		"NoNotifications": twinLunchUser.NoNotifications,
+		"PendingIntro":    twinLunchUser.PendingIntro,
+		"PriorityRounds":  twinLunchUser.PriorityRounds,
+	}))
+}
+
+func handleWhoisCommand(command slack.SlashCommand) {
+	var matches = userRegexp.FindAllStringSubmatch(command.Text, -1)
+
+	if len(matches) != 1 {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "whoisUsage", nil))
+		return
+	}
+
+	var user = matches[0][1]
+
+	var twinLunch, ok = twinLunches.Get(user)
+	if !ok {
+		sendBotMessageToUser(command.UserID, messageTo(command.UserID, "userHasNoTwinLunch", messageData{"User": user}))
+		return
+	}
+
+	var partners = twinLunch.Partners(user)
+	sendBotMessageToUser(command.UserID, messageTo(command.UserID, "whois", messageData{"User": user, "Partner": partners[0], "Others": partners[1:]}))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestUpdateTwinLunchUser(t *testing.T) {
+	var _, fd = setupFakes(t)
+	fd.delayNext("Put", 200*time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := updateTwinLunchUser("U1", func(twinLunchUser *TwinLunchUser) { twinLunchUser.NoNotifications = true }); err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := updateTwinLunchUser("U1", func(twinLunchUser *TwinLunchUser) { twinLunchUser.PriorityRounds = 2 }); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// the users can be read while a write is slow
+	time.Sleep(50 * time.Millisecond)
+	var start = time.Now()
+	getTwinLunchUser("U2")
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("reading a user took %s during a slow write", elapsed)
+	}
+
+	wg.Wait()
+
+	var want = TwinLunchUser{NoNotifications: true, PriorityRounds: 2}
+	if cached := getTwinLunchUser("U1"); cached != want {
+		t.Errorf("cached user = %+v, want %+v", cached, want)
+	}
+	var stored TwinLunchUser
+	if err := fd.Get(context.Background(), twinLunchUserKey("U1"), &stored); err != nil || stored != want {
+		t.Errorf("stored user = %+v (%v), want %+v", stored, err, want)
+	}
+}
+
+func TestCooldownOnEnd(t *testing.T) {
+	var savedCooldown = repairCooldown
+	repairCooldown = time.Hour
+	t.Cleanup(func() { repairCooldown = savedCooldown })
+
+	var tests = []struct {
+		name         string
+		command      slack.SlashCommand
+		wantCooldown bool
+	}{
+		{"remove", slack.SlashCommand{Command: "/twinlunch-remove", UserID: "UADMIN", Text: "<@U1> <@U2>"}, true},
+		{"quit", slack.SlashCommand{Command: "/twinlunch-quit", UserID: "U1"}, true},
+		{"clear", slack.SlashCommand{Command: "/twinlunch-clear", UserID: "UADMIN"}, false},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			setupFakes(t)
+			twinLunchAdmins["UADMIN"] = struct{}{}
+			if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+				t.Fatal(err)
+			}
+
+			handleCommand(test.command)
+			deliveries.Wait()
+
+			if _, ok := twinLunches.Get("U1"); ok {
+				t.Fatal("twin lunch not ended")
+			}
+			for _, user := range []string{"U1", "U2"} {
+				if cooldown := inCooldown(user); cooldown != test.wantCooldown {
+					t.Errorf("cooldown of %s = %t, want %t", user, cooldown, test.wantCooldown)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	slackTimeout      = 30 * time.Second
+	watchdogThreshold = time.Minute
+
+	watchdogMu       sync.Mutex
+	watchdogSince    time.Time
+	watchdogEvent    string
+	watchdogReported bool
+)
+
+func watchdogBusy(event string) {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+
+	watchdogSince, watchdogEvent, watchdogReported = time.Now(), event, false
+}
+
+func watchdogIdle() {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+
+	if watchdogReported {
+		logger.Printf("run loop recovered after handling %s for %s", watchdogEvent, time.Since(watchdogSince))
+	}
+
+	watchdogSince, watchdogEvent, watchdogReported = time.Time{}, "", false
+}
+
+// runWatchdog logs when the run loop spends too much time processing a single event.
+func runWatchdog(ctx context.Context) {
+	var ticker = time.NewTicker(watchdogThreshold / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		watchdogMu.Lock()
+		if !watchdogSince.IsZero() && !watchdogReported && time.Since(watchdogSince) > watchdogThreshold {
+			logger.Printf("warning: run loop has been handling %s for %s", watchdogEvent, time.Since(watchdogSince))
+			watchdogReported = true
+		}
+		watchdogMu.Unlock()
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestSlowHandler(t *testing.T) {
+	var fs, fd = setupFakes(t)
+	var logs = captureLogs(t)
+	twinLunchAdmins["UADMIN"] = struct{}{}
+
+	var savedTimeout, savedRetries, savedThreshold = datastoreTimeout, datastoreRetries, watchdogThreshold
+	datastoreTimeout, datastoreRetries, watchdogThreshold = 200*time.Millisecond, 0, 40*time.Millisecond
+	t.Cleanup(func() {
+		datastoreTimeout, datastoreRetries, watchdogThreshold = savedTimeout, savedRetries, savedThreshold
+	})
+
+	// the audit of the command never completes, it is cut by the datastore timeout
+	fd.delayNext("Put", time.Hour)
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go runWatchdog(ctx)
+
+	var messages = make(chan *slackevents.MessageEvent, 1)
+	var commands = make(chan slack.SlashCommand, 1)
+	var done = make(chan struct{})
+	loops.Add(1)
+	go func() {
+		defer close(done)
+		run(messages, commands, nil, nil, nil)
+	}()
+
+	var start = time.Now()
+	commands <- slack.SlashCommand{Command: "/twinlunch-list", UserID: "UADMIN"}
+	messages <- &slackevents.MessageEvent{Channel: dmChannel("U1"), User: "U1", ChannelType: slack.TYPE_IM, Text: "hello"}
+	close(messages)
+	close(commands)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run loop is wedged by the slow handler")
+	}
+	deliveries.Wait()
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("handling the events took %s, want them bounded by the datastore timeout", elapsed)
+	}
+	if replies := fs.messagesTo(dmChannel("U1")); len(replies) != 1 {
+		t.Errorf("replies to the message after the slow command = %q, want one", replies)
+	}
+	if output := logs.String(); !strings.Contains(output, "warning: run loop has been handling /twinlunch-list") || !strings.Contains(output, "run loop recovered") {
+		t.Errorf("logs = %q, want the watchdog to report the slow command and its recovery", output)
+	}
+}
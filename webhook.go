@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	workflowEventTwinLunchAdded   = "twin_lunch_added"
+	workflowEventTwinLunchRemoved = "twin_lunch_removed"
+)
+
+var (
+	workflowWebhookURL string
+	// when set, the webhook replaces the intro DMs instead of complementing them
+	workflowWebhookOnly bool
+
+	workflowWebhookClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// WorkflowEvent is the JSON payload posted to the Workflow Builder webhook.
+// Workflow Builder only accepts flat string variables.
+type WorkflowEvent struct {
+	Event string `json:"event"`
+	User1 string `json:"user_1"`
+	User2 string `json:"user_2"`
+	// Others lists the other members of groups, separated by commas
+	Others string `json:"others,omitempty"`
+	Admin  string `json:"admin"`
+}
+
+func newWorkflowEvent(event string, twinLunch *TwinLunch, admin string) WorkflowEvent {
+	return WorkflowEvent{event, twinLunch.User1, twinLunch.User2, strings.Join(twinLunch.Others, ","), admin}
+}
+
+func publishWorkflowEvent(event WorkflowEvent) {
+	if workflowWebhookURL == "" {
+		return
+	}
+
+	go func() {
+		if err := postWorkflowEvent(event); err != nil {
+			logger.Println(err)
+		}
+	}()
+}
+
+func postWorkflowEvent(event WorkflowEvent) error {
+	var body, err = json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding workflow event: %w", err)
+	}
+
+	resp, err := workflowWebhookClient.Post(workflowWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting workflow event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error posting workflow event: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPublishWorkflowEvent(t *testing.T) {
+	var tests = []struct {
+		name       string
+		only       bool
+		status     int
+		wantIntros int
+	}{
+		{"webhook and intros", false, http.StatusOK, 1},
+		{"webhook only", true, http.StatusOK, 0},
+		{"webhook failure", false, http.StatusInternalServerError, 1},
+	}
+
+	for _, test := range tests {
+		var test = test
+		t.Run(test.name, func(t *testing.T) {
+			var fs, fd = setupFakes(t)
+
+			var events = make(chan WorkflowEvent, 1)
+			var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event WorkflowEvent
+				if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(test.status)
+				events <- event
+			}))
+			defer server.Close()
+
+			var savedURL, savedOnly = workflowWebhookURL, workflowWebhookOnly
+			workflowWebhookURL, workflowWebhookOnly = server.URL, test.only
+			defer func() { workflowWebhookURL, workflowWebhookOnly = savedURL, savedOnly }()
+
+			if _, err := createTwinLunch([]string{"U1", "U2"}, 0, "UADMIN"); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case event := <-events:
+				var want = WorkflowEvent{Event: workflowEventTwinLunchAdded, User1: "U1", User2: "U2", Admin: "UADMIN"}
+				if !reflect.DeepEqual(event, want) {
+					t.Errorf("event = %+v, want %+v", event, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no event posted to the webhook")
+			}
+
+			deliveries.Wait()
+
+			for _, user := range []string{"U1", "U2"} {
+				if intros := fs.messagesTo(dmChannel(user)); len(intros) != test.wantIntros {
+					t.Errorf("intros of %s = %q, want %d", user, intros, test.wantIntros)
+				}
+			}
+			if stored := storedTwinLunches(t, fd); len(stored) != 1 {
+				t.Errorf("stored twin lunches = %q, want the pairing even if the webhook fails", stored)
+			}
+		})
+	}
+}